@@ -5,6 +5,8 @@ import (
 	"github.com/joho/godotenv"
 	"log"
 	"os"
+	"strconv"
+	"strings"
 )
 
 type S3 struct {
@@ -13,10 +15,40 @@ type S3 struct {
 	URL       string
 	Bucket    string
 }
+
+// Mail is SMTP server of outgoing mails, without Addr mails are only logged.
+// ResetURL is the page of password reset, the token is added to it as query parameter
+type Mail struct {
+	Addr     string
+	Username string
+	Password string
+	From     string
+	ResetURL string
+}
+
+// Admin is created on start when there is no admin yet, users are created by admins only
+type Admin struct {
+	Login    string
+	Password string
+}
+
+// Password is the strength policy of new passwords
+type Password struct {
+	MinLength        int
+	RequireMixedCase bool
+	RequireDigit     bool
+	RequireSymbol    bool
+}
+
 type Configs struct {
 	DatabaseURI string
 	Address     string
+	// AllowedOrigins may send credentials cross-origin, cookie sessions also need them to be same-site with API
+	AllowedOrigins []string
 	S3
+	Admin
+	Mail
+	Password
 }
 
 func Parse() *Configs {
@@ -26,13 +58,60 @@ func Parse() *Configs {
 	}
 
 	return &Configs{
-		DatabaseURI: os.Getenv("DATABASE_URL"),
-		Address:     fmt.Sprintf(":%s", os.Getenv("PORT")),
+		DatabaseURI:    os.Getenv("DATABASE_URL"),
+		Address:        fmt.Sprintf(":%s", os.Getenv("PORT")),
+		AllowedOrigins: listEnv("CORS_ALLOWED_ORIGINS"),
 		S3: S3{
 			AccessKey: os.Getenv("MINIO_ACCESS_KEY"),
 			SecretKey: os.Getenv("MINIO_SECRET_KEY"),
 			URL:       os.Getenv("MINIO_URL"),
 			Bucket:    os.Getenv("MINIO_BUCKET"),
 		},
+		Admin: Admin{
+			Login:    os.Getenv("ADMIN_LOGIN"),
+			Password: os.Getenv("ADMIN_PASSWORD"),
+		},
+		Mail: Mail{
+			Addr:     os.Getenv("SMTP_ADDR"),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     os.Getenv("MAIL_FROM"),
+			ResetURL: os.Getenv("PASSWORD_RESET_URL"),
+		},
+		Password: Password{
+			MinLength:        intEnv("PASSWORD_MIN_LENGTH", 8),
+			RequireMixedCase: boolEnv("PASSWORD_REQUIRE_MIXED_CASE", false),
+			RequireDigit:     boolEnv("PASSWORD_REQUIRE_DIGIT", true),
+			RequireSymbol:    boolEnv("PASSWORD_REQUIRE_SYMBOL", false),
+		},
+	}
+}
+
+// intEnv reads integer variable, unset or broken one gives fallback
+func intEnv(key string, fallback int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// listEnv reads comma separated variable, unset one gives nil
+func listEnv(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// boolEnv reads boolean variable like true or 0, unset or broken one gives fallback
+func boolEnv(key string, fallback bool) bool {
+	value, err := strconv.ParseBool(os.Getenv(key))
+	if err != nil {
+		return fallback
 	}
+	return value
 }
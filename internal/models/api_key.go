@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+)
+
+// APIKey lets scripts call API as its user without password. Only hash of the key is stored,
+// Prefix is kept to tell keys apart in lists. Scopes limit the key on top of the role of its user
+type APIKey struct {
+	gorm.Model
+	Name       string         `gorm:"not null"`
+	UserID     uint           `gorm:"not null;index"`
+	User       User           `gorm:"foreignKey:UserID"`
+	Prefix     string         `gorm:"not null"`
+	KeyHash    string         `gorm:"not null;uniqueIndex"`
+	Key        string         `gorm:"-"` // raw key, it is set only when key is issued
+	Scopes     pq.StringArray `gorm:"type:text[];default:'{}'"`
+	ExpiresAt  *time.Time     // nil key does not expire
+	LastUsedAt *time.Time
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Assignment is a published training or program which coach gave to athlete, exactly one of
+// TrainingID and ProgramID is set. Workouts logged by athlete since assignment count towards it
+type Assignment struct {
+	gorm.Model
+	CoachID    uint      `gorm:"not null;index"`
+	Coach      User      `gorm:"foreignKey:CoachID"`
+	AthleteID  uint      `gorm:"not null;index"`
+	Athlete    User      `gorm:"foreignKey:AthleteID"`
+	TrainingID *uint     `gorm:"index"`
+	Training   *Training `gorm:"foreignKey:TrainingID"`
+	ProgramID  *uint     `gorm:"index"`
+	Program    *Program  `gorm:"foreignKey:ProgramID"`
+	DueDate    time.Time `gorm:"type:date;not null;index"`
+	Notes      string    `gorm:"not null;default:''"`
+}
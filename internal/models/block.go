@@ -2,14 +2,22 @@ package models
 
 import "gorm.io/gorm"
 
+// Block is one version of block, all versions share LineageID which is the id of the first one.
+// Published version is frozen, editing it creates a new draft version.
+// Versions of one lineage share titles, titles of other lineages are checked by use cases
 type Block struct {
 	gorm.Model
-	TitleEn        string          `gorm:"unique;not null"`
-	TitleRu        string          `gorm:"unique;not null"`
-	TotalDuration  uint8           // minutes
-	OnTime         uint8           // seconds
-	RelaxTime      uint8           // seconds
-	Draft          bool            `gorm:"default:true"`
+	TitleEn       string `gorm:"not null;uniqueIndex:idx_blocks_title_en_version"`
+	TitleRu       string `gorm:"not null;uniqueIndex:idx_blocks_title_ru_version"`
+	TotalDuration uint8  // minutes
+	OnTime        uint8  // seconds
+	RelaxTime     uint8  // seconds
+	Draft         bool   `gorm:"default:true"`
+	LineageID     uint   `gorm:"not null;default:0;index"`
+	Version       uint   `gorm:"not null;default:1;uniqueIndex:idx_blocks_title_en_version;uniqueIndex:idx_blocks_title_ru_version"`
+	// OwnerID is nil for content created before owners, only admins can change such content
+	OwnerID        *uint           `gorm:"index"`
+	Visibility     string          `gorm:"not null;default:'organization'"` // enum of ["private", "shared", "organization"]
 	Exercises      []Exercise      `gorm:"many2many:exercises_blocks;"`
 	ExerciseBlocks []ExerciseBlock `gorm:"foreignKey:BlockID;references:ID"`
 }
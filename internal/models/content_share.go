@@ -0,0 +1,10 @@
+package models
+
+// ContentShare gives user access to content with shared visibility.
+// ContentID is the id of exercise or program or the lineage id of block or training, so every version is shared
+type ContentShare struct {
+	ID        uint   `gorm:"primaryKey"`
+	Kind      string `gorm:"not null;uniqueIndex:idx_content_shares_user"` // enum of ["exercise", "block", "training", "program"]
+	ContentID uint   `gorm:"not null;uniqueIndex:idx_content_shares_user"`
+	UserID    uint   `gorm:"not null;uniqueIndex:idx_content_shares_user;index"`
+}
@@ -11,4 +11,15 @@ type Exercise struct {
 	TitleRu  string         `gorm:"not null"`
 	Filename string         `gorm:"unique;not null"`
 	Tips     pq.StringArray `gorm:"type:text[];default:'{}'"`
+	// Unilateral exercise is done for left and right side separately
+	Unilateral   bool           `gorm:"default:false"`
+	MuscleGroups pq.StringArray `gorm:"type:text[];default:'{}'"`
+	// Equipment is needed to do exercise, empty for bodyweight exercises
+	Equipment pq.StringArray `gorm:"type:text[];default:'{}'"`
+	// Difficulty is from 1 (beginner) to 3 (advanced)
+	Difficulty uint8 `gorm:"not null;default:1"`
+	Tags       []Tag `gorm:"many2many:exercises_tags;"`
+	// OwnerID is nil for content created before owners, only admins can change such content
+	OwnerID    *uint  `gorm:"index"`
+	Visibility string `gorm:"not null;default:'organization'"` // enum of ["private", "shared", "organization"]
 }
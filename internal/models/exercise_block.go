@@ -4,10 +4,12 @@ import (
 	"gorm.io/gorm"
 )
 
+// ExerciseBlock is a slot of block, the same exercise can take several slots
 type ExerciseBlock struct {
 	gorm.Model
-	ExerciseID    uint `gorm:"primaryKey"`
-	BlockID       uint `gorm:"primaryKey"`
+	ExerciseID    uint `gorm:"not null;index"`
+	BlockID       uint `gorm:"not null;index"`
 	ExerciseOrder uint `gorm:"not_null;default:0;"`
 	Side          string
+	PairSlotID    *uint `gorm:"index"` // the other side slot of unilateral exercise
 }
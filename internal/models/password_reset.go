@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// PasswordReset is a single-use token mailed to user to set a new password, only its hash is stored
+type PasswordReset struct {
+	ID        uint   `gorm:"primaryKey"`
+	UserID    uint   `gorm:"not null;index"`
+	User      User   `gorm:"foreignKey:UserID"`
+	TokenHash string `gorm:"not null;uniqueIndex"`
+	CreatedAt time.Time
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+}
@@ -0,0 +1,16 @@
+package models
+
+import "gorm.io/gorm"
+
+// Program is a calendar of Weeks weeks, every week has 7 days and Days fill some of them
+type Program struct {
+	gorm.Model
+	TitleEn string `gorm:"not null"`
+	TitleRu string `gorm:"not null"`
+	Draft   bool   `gorm:"default:true"`
+	Weeks   uint8  `gorm:"not null;default:1"`
+	// OwnerID is nil for programs created before owners, only admins can change such programs
+	OwnerID    *uint        `gorm:"index"`
+	Visibility string       `gorm:"not null;default:'organization'"` // enum of ["private", "shared", "organization"]
+	Days       []ProgramDay `gorm:"foreignKey:ProgramID;references:ID"`
+}
@@ -0,0 +1,10 @@
+package models
+
+// ProgramDay is a day slot of program, nil TrainingID makes it a rest day
+type ProgramDay struct {
+	ID         uint  `gorm:"primaryKey"`
+	ProgramID  uint  `gorm:"not null;uniqueIndex:idx_program_days_day"`
+	Week       uint8 `gorm:"not null;uniqueIndex:idx_program_days_day"` // starts from 0
+	Day        uint8 `gorm:"not null;uniqueIndex:idx_program_days_day"` // from 0 (monday) to 6 (sunday)
+	TrainingID *uint `gorm:"index"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ScheduledSession is a planned training of user. StartsAt is the first occurrence,
+// RRule repeats it keeping the same local time in Timezone
+type ScheduledSession struct {
+	gorm.Model
+	UserID     uint      `gorm:"not null;index"`
+	TrainingID uint      `gorm:"not null;index"`
+	Training   Training  `gorm:"foreignKey:TrainingID"`
+	StartsAt   time.Time `gorm:"not null;index"`
+	Timezone   string    `gorm:"not null;default:'UTC'"` // IANA name like Europe/Moscow
+	RRule      string    `gorm:"not null;default:''"`    // RFC 5545 rule without RRULE: prefix, empty for a single session
+	Notes      string    `gorm:"not null;default:''"`
+}
@@ -1,9 +1,24 @@
 package models
 
-import "github.com/jackc/pgx/v5/pgtype"
+import (
+	"time"
 
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Session is a login of user on one device. ID names it in lists of sessions, the secret token is given
+// to the device once and only its hash is stored. Session ends at ExpiresAt or when it is not used
+// for a while after LastSeenAt
 type Session struct {
-	ID     pgtype.UUID `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
-	UserID uint        `gorm:"not null;index"`
-	User   User        `gorm:"foreignKey:UserID;not null"`
+	ID         pgtype.UUID `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	TokenHash  string      `gorm:"uniqueIndex"`
+	Token      string      `gorm:"-"` // raw token, it is set only when session is created
+	UserID     uint        `gorm:"not null;index"`
+	User       User        `gorm:"foreignKey:UserID;not null"`
+	DeviceID   string      `gorm:"not null;default:'';index"` // kept by client, empty for clients which do not keep it
+	UserAgent  string      `gorm:"not null;default:''"`
+	IP         string      `gorm:"not null;default:''"` // address of login, it is a label and not a check
+	CreatedAt  time.Time
+	LastSeenAt time.Time `gorm:"not null;default:CURRENT_TIMESTAMP"`
+	ExpiresAt  time.Time `gorm:"not null;default:CURRENT_TIMESTAMP;index"`
 }
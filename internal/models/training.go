@@ -2,11 +2,18 @@ package models
 
 import "gorm.io/gorm"
 
+// Training is one version of training, all versions share LineageID which is the id of the first one.
+// TrainingBlocks pin exact block versions
 type Training struct {
 	gorm.Model
-	TitleEn        string          `gorm:"not null"`
-	TitleRu        string          `gorm:"not null"`
-	Draft          bool            `gorm:"default:true"`
+	TitleEn   string `gorm:"not null"`
+	TitleRu   string `gorm:"not null"`
+	Draft     bool   `gorm:"default:true"`
+	LineageID uint   `gorm:"not null;default:0;index"`
+	Version   uint   `gorm:"not null;default:1"`
+	// OwnerID is nil for content created before owners, only admins can change such content
+	OwnerID        *uint           `gorm:"index"`
+	Visibility     string          `gorm:"not null;default:'organization'"` // enum of ["private", "shared", "organization"]
 	Blocks         []Block         `gorm:"many2many:trainings_blocks;"`
 	TrainingBlocks []TrainingBlock `gorm:"foreignKey:TrainingID;references:ID"`
 }
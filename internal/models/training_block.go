@@ -7,4 +7,7 @@ type TrainingBlock struct {
 	TrainingID uint `gorm:"primaryKey"`
 	BlockID    uint `gorm:"primaryKey"`
 	BlockOrder uint `gorm:"not_null;default:0;"`
+	// TransitionRest is rest after every round of block before the next one, seconds
+	TransitionRest uint16 `gorm:"not null;default:0"`
+	Repeats        uint8  `gorm:"not null;default:1"` // rounds of block in a row
 }
@@ -4,6 +4,11 @@ import "gorm.io/gorm"
 
 type User struct {
 	gorm.Model
-	Login        string `gorm:"unique;not null"`
-	PasswordHash string `gorm:"unique;not null"`
+	Login        string  `gorm:"unique;not null"`
+	Email        *string `gorm:"uniqueIndex"` // password reset mails are sent to it
+	PasswordHash string  `gorm:"unique;not null"`
+	Role         string  `gorm:"not null;default:'athlete'"` // enum of ["admin", "coach", "athlete"]
+	Disabled     bool    `gorm:"not null;default:false"`
+	// CalendarTokenHash is sha256 of the secret token of iCalendar feed, the token itself is shown once
+	CalendarTokenHash *string `gorm:"uniqueIndex"`
 }
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// WorkoutLog records training which was actually done. TrainingID is the exact published version,
+// every result keeps the block version pinned by it
+type WorkoutLog struct {
+	gorm.Model
+	UserID     uint            `gorm:"not null;index:idx_workout_logs_user_started,priority:1"`
+	TrainingID uint            `gorm:"not null;index"`
+	Training   Training        `gorm:"foreignKey:TrainingID"`
+	RunID      *uint           `gorm:"uniqueIndex:idx_workout_logs_run,where:deleted_at IS NULL"` // run the log was made of, once
+	StartedAt  time.Time       `gorm:"not null;index:idx_workout_logs_user_started,priority:2"`
+	FinishedAt time.Time       `gorm:"not null"`
+	Notes      string          `gorm:"not null;default:''"`
+	Results    []WorkoutResult `gorm:"foreignKey:LogID;references:ID"`
+}
@@ -0,0 +1,16 @@
+package models
+
+// WorkoutResult is a result of one slot in one round of block, nil fields were not recorded
+type WorkoutResult struct {
+	ID         uint   `gorm:"primaryKey"`
+	LogID      uint   `gorm:"not null;index;index:idx_workout_results_exercise_log,priority:2"`
+	BlockID    uint   `gorm:"not null;index"`
+	SlotID     uint   `gorm:"not null"` // ExerciseBlock id
+	ExerciseID uint   `gorm:"not null;index:idx_workout_results_exercise_log,priority:1"`
+	Round      uint8  `gorm:"not null;default:1"`
+	Side       string `gorm:"not null;default:''"`
+	Reps       *uint16
+	Load       *float32 // kg
+	Skipped    bool     `gorm:"not null;default:false"`
+	RPE        *uint8   // rate of perceived exertion from 1 to 10
+}
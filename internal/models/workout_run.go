@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+)
+
+// WorkoutRun is a playback of exact training version. Position in timeline and elapsed active time
+// are stored as of ResumedAt, while running both grow with time since ResumedAt
+type WorkoutRun struct {
+	gorm.Model
+	UserID          uint       `gorm:"not null;index"`
+	TrainingID      uint       `gorm:"not null;index"`
+	Status          string     `gorm:"not null;default:'running'"` // running, paused or finished
+	PositionMs      int64      `gorm:"not null;default:0"`
+	ElapsedMs       int64      `gorm:"not null;default:0"`
+	ResumedAt       *time.Time // nil while paused or finished
+	FinishedAt      *time.Time
+	SkippedSegments pq.Int64Array `gorm:"type:bigint[];default:'{}'"` // indexes of skipped work segments
+}
@@ -2,19 +2,31 @@ package presenters
 
 import (
 	"bf_me/internal/models"
+	"bf_me/internal/services"
+	"bf_me/internal/use_cases"
+	"fmt"
 	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
 type Exercise struct {
-	ID        uint     `json:"id"`
-	CreatedAt string   `json:"createdAt"`
-	TitleEn   string   `json:"titleEn"`
-	TitleRu   string   `json:"titleRu"`
-	Filename  string   `json:"filename"`
-	Tips      []string `json:"tips"`
-	Tags      []string `json:"tagIds,omitempty;"`
+	ID           uint     `json:"id"`
+	CreatedAt    string   `json:"createdAt"`
+	TitleEn      string   `json:"titleEn"`
+	TitleRu      string   `json:"titleRu"`
+	Filename     string   `json:"filename"`
+	Tips         []string `json:"tips"`
+	Unilateral   bool     `json:"unilateral"`
+	MuscleGroups []string `json:"muscleGroups"`
+	Equipment    []string `json:"equipment"`
+	Difficulty   uint8    `json:"difficulty"`
+	TagIDs       []uint   `json:"tagIds"`
+	OwnerID      *uint    `json:"ownerId"`
+	Visibility   string   `json:"visibility"`
 }
 
 type Presenter struct {
@@ -25,38 +37,81 @@ func NewPresenter() *Presenter {
 }
 
 func (p *Presenter) Exercise(e *models.Exercise) *Exercise {
-	//todo tags
+	tagIDs := make([]uint, len(e.Tags))
+	for i, tag := range e.Tags {
+		tagIDs[i] = tag.ID
+	}
+	muscleGroups := []string(e.MuscleGroups)
+	if muscleGroups == nil {
+		muscleGroups = []string{}
+	}
+	equipment := []string(e.Equipment)
+	if equipment == nil {
+		equipment = []string{}
+	}
 	return &Exercise{
-		ID:        e.ID,
-		CreatedAt: e.CreatedAt.Format("January 2, 2006"),
-		TitleEn:   e.TitleEn,
-		TitleRu:   e.TitleRu,
-		Filename:  e.Filename,
-		Tips:      e.Tips,
+		ID:           e.ID,
+		CreatedAt:    e.CreatedAt.Format("January 2, 2006"),
+		TitleEn:      e.TitleEn,
+		TitleRu:      e.TitleRu,
+		Filename:     e.Filename,
+		Tips:         e.Tips,
+		Unilateral:   e.Unilateral,
+		MuscleGroups: muscleGroups,
+		Equipment:    equipment,
+		Difficulty:   e.Difficulty,
+		TagIDs:       tagIDs,
+		OwnerID:      e.OwnerID,
+		Visibility:   e.Visibility,
 	}
 }
 
 func (p *Presenter) Exercises(es []*models.Exercise) []*Exercise {
 	exercises := make([]*Exercise, len(es))
 	for i, e := range es {
-		exercises[i] = &Exercise{
-			ID:        e.ID,
-			CreatedAt: e.CreatedAt.Format("January 2, 2006"),
-			TitleEn:   e.TitleEn,
-			TitleRu:   e.TitleRu,
-			Filename:  e.Filename,
-			Tips:      e.Tips,
-		}
+		exercises[i] = p.Exercise(e)
 	}
 	return exercises
 }
 
+// Session has either token for Authorization header or CSRF token of cookie session
 type Session struct {
-	Token pgtype.UUID `json:"token"`
+	Token     string `json:"token,omitempty"`
+	CSRFToken string `json:"csrfToken,omitempty"`
+}
+
+func (p *Presenter) Session(s *models.Session, cookie bool) *Session {
+	if cookie {
+		return &Session{CSRFToken: services.CSRFToken(s.Token)}
+	}
+	return &Session{Token: s.Token}
+}
+
+// Device is a session in list of sessions, it has no token
+type Device struct {
+	ID         pgtype.UUID `json:"id"`
+	UserAgent  string      `json:"userAgent"`
+	IP         string      `json:"ip"`
+	CreatedAt  string      `json:"createdAt"`  // RFC 3339
+	LastSeenAt string      `json:"lastSeenAt"` // RFC 3339
+	ExpiresAt  string      `json:"expiresAt"`  // RFC 3339, absolute expiry
+	Current    bool        `json:"current"`
 }
 
-func (p *Presenter) Session(s *models.Session) *Session {
-	return &Session{Token: s.ID}
+func (p *Presenter) Devices(sessions []models.Session, current *models.Session) []Device {
+	arr := make([]Device, len(sessions))
+	for i, s := range sessions {
+		arr[i] = Device{
+			ID:         s.ID,
+			UserAgent:  s.UserAgent,
+			IP:         s.IP,
+			CreatedAt:  s.CreatedAt.UTC().Format(time.RFC3339),
+			LastSeenAt: s.LastSeenAt.UTC().Format(time.RFC3339),
+			ExpiresAt:  s.ExpiresAt.UTC().Format(time.RFC3339),
+			Current:    s.ID == current.ID,
+		}
+	}
+	return arr
 }
 
 type Block struct {
@@ -68,16 +123,23 @@ type Block struct {
 	OnTime        uint8           `json:"onTime"`        // seconds
 	RelaxTime     uint8           `json:"relaxTime"`     // seconds
 	Draft         bool            `json:"draft"`
+	LineageID     uint            `json:"lineageId"`
+	Version       uint            `json:"version"`
+	OwnerID       *uint           `json:"ownerId"`
+	Visibility    string          `json:"visibility"`
 	Exercises     []BlockExercise `json:"exercises,omitempty;"`
+	Summary       *Summary        `json:"summary,omitempty"`
 }
 
 type BlockExercise struct {
-	ID       uint   `json:"id"` // exercise id
-	Order    uint   `json:"order"`
-	Side     string `json:"side"`
-	TitleEn  string `json:"titleEn"`
-	TitleRu  string `json:"titleRu"`
-	Filename string `json:"filename"`
+	SlotID     uint   `json:"slotId"`
+	PairSlotID *uint  `json:"pairSlotId,omitempty"`
+	ID         uint   `json:"id"` // exercise id
+	Order      uint   `json:"order"`
+	Side       string `json:"side"`
+	TitleEn    string `json:"titleEn"`
+	TitleRu    string `json:"titleRu"`
+	Filename   string `json:"filename"`
 }
 
 func (p *Presenter) Block(block models.Block) Block {
@@ -90,7 +152,12 @@ func (p *Presenter) Block(block models.Block) Block {
 		OnTime:        block.OnTime,
 		RelaxTime:     block.RelaxTime,
 		Draft:         block.Draft,
+		LineageID:     block.LineageID,
+		Version:       block.Version,
+		OwnerID:       block.OwnerID,
+		Visibility:    block.Visibility,
 		Exercises:     p.buildBlockExercises(block),
+		Summary:       p.Summary(use_cases.Summarize(use_cases.BlockTimeline(&block))),
 	}
 }
 
@@ -104,12 +171,14 @@ func (p *Presenter) buildBlockExercises(block models.Block) []BlockExercise {
 		exerciseID := eb.ExerciseID
 		exercise := p.takeExerciseByID(block.Exercises, exerciseID)
 		arr[i] = BlockExercise{
-			ID:       eb.ExerciseID,
-			Order:    uint(i),
-			Side:     eb.Side,
-			TitleEn:  exercise.TitleEn,
-			TitleRu:  exercise.TitleRu,
-			Filename: exercise.Filename,
+			SlotID:     eb.ID,
+			PairSlotID: eb.PairSlotID,
+			ID:         eb.ExerciseID,
+			Order:      uint(i),
+			Side:       eb.Side,
+			TitleEn:    exercise.TitleEn,
+			TitleRu:    exercise.TitleRu,
+			Filename:   exercise.Filename,
 		}
 	}
 	return arr
@@ -133,29 +202,916 @@ func (p *Presenter) Blocks(bs []models.Block) []Block {
 }
 
 type Training struct {
-	ID        uint    `json:"id"`
-	CreatedAt string  `json:"createdAt"`
-	TitleEn   string  `json:"titleEn"`
-	TitleRu   string  `json:"titleRu"`
-	Draft     bool    `json:"draft"`
-	Blocks    []Block `json:"blocks"`
+	ID         uint            `json:"id"`
+	CreatedAt  string          `json:"createdAt"`
+	TitleEn    string          `json:"titleEn"`
+	TitleRu    string          `json:"titleRu"`
+	Draft      bool            `json:"draft"`
+	LineageID  uint            `json:"lineageId"`
+	Version    uint            `json:"version"`
+	OwnerID    *uint           `json:"ownerId"`
+	Visibility string          `json:"visibility"`
+	Blocks     []TrainingBlock `json:"blocks"`
+	Summary    *Summary        `json:"summary,omitempty"`
+}
+
+type TrainingBlock struct {
+	Block
+	SlotID         uint   `json:"slotId"`
+	Order          uint   `json:"order"`
+	TransitionRest uint16 `json:"transitionRest"` // seconds
+	Repeats        uint8  `json:"repeats"`
 }
 
 func (p *Presenter) Training(tr *models.Training, blocks []models.Block) Training {
+	training := p.trainingRow(tr, blocks)
+	training.Summary = p.Summary(use_cases.Summarize(use_cases.TrainingTimeline(tr, blocks)))
+	return training
+}
+
+// trainingRow presents training without summary, which needs its blocks loaded
+func (p *Presenter) trainingRow(tr *models.Training, blocks []models.Block) Training {
 	return Training{
-		ID:        tr.ID,
-		CreatedAt: tr.CreatedAt.Format("January 2, 2006"),
-		TitleEn:   tr.TitleEn,
-		TitleRu:   tr.TitleRu,
-		Draft:     tr.Draft,
-		Blocks:    p.Blocks(blocks),
+		ID:         tr.ID,
+		CreatedAt:  tr.CreatedAt.Format("January 2, 2006"),
+		TitleEn:    tr.TitleEn,
+		TitleRu:    tr.TitleRu,
+		Draft:      tr.Draft,
+		LineageID:  tr.LineageID,
+		Version:    tr.Version,
+		OwnerID:    tr.OwnerID,
+		Visibility: tr.Visibility,
+		Blocks:     p.buildTrainingBlocks(tr, blocks),
+	}
+}
+
+func (p *Presenter) buildTrainingBlocks(tr *models.Training, blocks []models.Block) []TrainingBlock {
+	tbs := slices.Clone(tr.TrainingBlocks)
+	slices.SortFunc(tbs, func(a, b models.TrainingBlock) int {
+		return int(a.BlockOrder) - int(b.BlockOrder)
+	})
+
+	arr := make([]TrainingBlock, 0, len(tbs))
+	for _, tb := range tbs {
+		index := slices.IndexFunc(blocks, func(b models.Block) bool {
+			return b.ID == tb.BlockID
+		})
+		if index == -1 {
+			continue
+		}
+		arr = append(arr, TrainingBlock{
+			Block:          p.Block(blocks[index]),
+			SlotID:         tb.ID,
+			Order:          uint(len(arr)),
+			TransitionRest: tb.TransitionRest,
+			Repeats:        tb.Repeats,
+		})
 	}
+	return arr
 }
 
 func (p *Presenter) Trainings(trs []*models.Training) []Training {
 	arrTrs := make([]Training, len(trs))
 	for i, tr := range trs {
-		arrTrs[i] = p.Training(tr, []models.Block{})
+		// blocks are not loaded for the list, so summary is left out
+		arrTrs[i] = p.trainingRow(tr, []models.Block{})
 	}
 	return arrTrs
 }
+
+type Issue struct {
+	Code     string `json:"code"`
+	Severity string `json:"severity"`
+	Entity   Entity `json:"entity"`
+	Message  string `json:"message"`
+}
+
+type Entity struct {
+	Type string `json:"type"`
+	ID   uint   `json:"id"`
+}
+
+type Validation struct {
+	Ready  bool    `json:"ready"`
+	Issues []Issue `json:"issues"`
+}
+
+func (p *Presenter) Validation(issues []use_cases.Issue) Validation {
+	arr := make([]Issue, len(issues))
+	for i, issue := range issues {
+		arr[i] = Issue{
+			Code:     issue.Code,
+			Severity: issue.Severity,
+			Entity:   Entity{Type: issue.EntityType, ID: issue.EntityID},
+			Message:  issue.Message,
+		}
+	}
+	return Validation{
+		Ready:  !use_cases.HasErrors(issues),
+		Issues: arr,
+	}
+}
+
+type Timeline struct {
+	ID            uint      `json:"id"`            // block or training id
+	TotalDuration uint      `json:"totalDuration"` // seconds
+	Segments      []Segment `json:"segments"`
+}
+
+type Segment struct {
+	Kind       string `json:"kind"`     // work, rest or transition
+	Start      uint   `json:"start"`    // seconds from the beginning
+	Duration   uint   `json:"duration"` // seconds
+	BlockID    uint   `json:"blockId"`
+	Round      uint   `json:"round,omitempty"`
+	SlotID     uint   `json:"slotId,omitempty"`
+	ExerciseID uint   `json:"exerciseId,omitempty"`
+	Side       string `json:"side,omitempty"`
+	TitleEn    string `json:"titleEn,omitempty"`
+	TitleRu    string `json:"titleRu,omitempty"`
+	Filename   string `json:"filename,omitempty"`
+}
+
+func (p *Presenter) Timeline(id uint, segments []use_cases.Segment) Timeline {
+	return Timeline{
+		ID:            id,
+		TotalDuration: use_cases.TimelineDuration(segments),
+		Segments:      p.Segments(segments),
+	}
+}
+
+func (p *Presenter) Segments(segments []use_cases.Segment) []Segment {
+	arr := make([]Segment, len(segments))
+	for i, s := range segments {
+		arr[i] = Segment{
+			Kind:     s.Kind,
+			Start:    s.Start,
+			Duration: s.Duration,
+			BlockID:  s.BlockID,
+			Round:    s.Round,
+			SlotID:   s.SlotID,
+			Side:     s.Side,
+		}
+		if s.Exercise != nil {
+			arr[i].ExerciseID = s.Exercise.ID
+			arr[i].TitleEn = s.Exercise.TitleEn
+			arr[i].TitleRu = s.Exercise.TitleRu
+			arr[i].Filename = s.Exercise.Filename
+		}
+	}
+	return arr
+}
+
+type BlockUpgrade struct {
+	Position           uint          `json:"position"`
+	FromBlockID        uint          `json:"fromBlockId"`
+	FromVersion        uint          `json:"fromVersion"`
+	ToBlockID          uint          `json:"toBlockId"`
+	ToVersion          uint          `json:"toVersion"`
+	Changes            []FieldChange `json:"changes"`
+	AddedExerciseIDs   []uint        `json:"addedExerciseIds"`
+	RemovedExerciseIDs []uint        `json:"removedExerciseIds"`
+}
+
+type FieldChange struct {
+	Field string `json:"field"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
+
+type TrainingUpgrade struct {
+	Training Training       `json:"training"`
+	Upgrades []BlockUpgrade `json:"upgrades"`
+}
+
+func (p *Presenter) BlockUpgrades(upgrades []use_cases.BlockUpgrade) []BlockUpgrade {
+	arr := make([]BlockUpgrade, len(upgrades))
+	for i, u := range upgrades {
+		changes := make([]FieldChange, len(u.Changes))
+		for j, c := range u.Changes {
+			changes[j] = FieldChange{Field: c.Field, From: c.From, To: c.To}
+		}
+		arr[i] = BlockUpgrade{
+			Position:           u.Position,
+			FromBlockID:        u.From.ID,
+			FromVersion:        u.From.Version,
+			ToBlockID:          u.To.ID,
+			ToVersion:          u.To.Version,
+			Changes:            changes,
+			AddedExerciseIDs:   u.AddedExerciseIDs,
+			RemovedExerciseIDs: u.RemovedExerciseIDs,
+		}
+	}
+	return arr
+}
+
+func (p *Presenter) TrainingUpgrade(tr *models.Training, blocks []models.Block, upgrades []use_cases.BlockUpgrade) TrainingUpgrade {
+	return TrainingUpgrade{
+		Training: p.Training(tr, blocks),
+		Upgrades: p.BlockUpgrades(upgrades),
+	}
+}
+
+type Summary struct {
+	TotalDuration  uint    `json:"totalDuration"`  // seconds
+	WorkTime       uint    `json:"workTime"`       // seconds
+	RestTime       uint    `json:"restTime"`       // seconds
+	TransitionTime uint    `json:"transitionTime"` // seconds
+	Slots          uint    `json:"slots"`
+	Exercises      uint    `json:"exercises"` // distinct exercises
+	Unilateral     Sides   `json:"unilateral"`
+	Tags           []Share `json:"tags"`
+	MuscleGroups   []Share `json:"muscleGroups"`
+}
+
+type Sides struct {
+	Left     uint `json:"left"`  // seconds
+	Right    uint `json:"right"` // seconds
+	Balanced bool `json:"balanced"`
+}
+
+type Share struct {
+	TagID    uint   `json:"tagId,omitempty"`
+	Name     string `json:"name"`
+	WorkTime uint   `json:"workTime"` // seconds
+	Percent  uint   `json:"percent"`  // of work time of block, training or period
+}
+
+func (p *Presenter) Summary(s use_cases.Summary) *Summary {
+	return &Summary{
+		TotalDuration:  s.TotalDuration,
+		WorkTime:       s.WorkTime,
+		RestTime:       s.RestTime,
+		TransitionTime: s.TransitionTime,
+		Slots:          s.Slots,
+		Exercises:      s.Exercises,
+		Unilateral:     Sides{Left: s.LeftTime, Right: s.RightTime, Balanced: s.Balanced()},
+		Tags:           p.shares(s.Tags, s.WorkTime),
+		MuscleGroups:   p.shares(s.MuscleGroups, s.WorkTime),
+	}
+}
+
+func (p *Presenter) shares(shares []use_cases.Share, workTime uint) []Share {
+	arr := make([]Share, len(shares))
+	for i, s := range shares {
+		arr[i] = Share{TagID: s.TagID, Name: s.Name, WorkTime: s.WorkTime}
+		if workTime != 0 {
+			arr[i].Percent = s.WorkTime * 100 / workTime
+		}
+	}
+	return arr
+}
+
+type Program struct {
+	ID         uint          `json:"id"`
+	CreatedAt  string        `json:"createdAt"`
+	TitleEn    string        `json:"titleEn"`
+	TitleRu    string        `json:"titleRu"`
+	Draft      bool          `json:"draft"`
+	OwnerID    *uint         `json:"ownerId"`
+	Visibility string        `json:"visibility"`
+	Weeks      []ProgramWeek `json:"weeks"`
+}
+
+type ProgramWeek struct {
+	Week uint8        `json:"week"` // starts from 0
+	Days []ProgramDay `json:"days"`
+}
+
+type ProgramDay struct {
+	Day        uint8            `json:"day"`              // from 0 (monday) to 6 (sunday)
+	Kind       string           `json:"kind"`             // training, rest or empty
+	SlotID     uint             `json:"slotId,omitempty"` // empty day has no slot
+	TrainingID uint             `json:"trainingId,omitempty"`
+	Training   *ProgramTraining `json:"training,omitempty"`
+}
+
+type ProgramTraining struct {
+	ID      uint   `json:"id"`
+	TitleEn string `json:"titleEn"`
+	TitleRu string `json:"titleRu"`
+	Draft   bool   `json:"draft"`
+	Version uint   `json:"version"`
+}
+
+// Program shows every day of every week, trainings are not required to list ids of them
+func (p *Presenter) Program(program *models.Program, trainings []models.Training) Program {
+	weeks := make([]ProgramWeek, program.Weeks)
+	for w := range weeks {
+		weeks[w] = ProgramWeek{Week: uint8(w), Days: make([]ProgramDay, use_cases.DaysInWeek)}
+		for d := range weeks[w].Days {
+			weeks[w].Days[d] = ProgramDay{Day: uint8(d), Kind: "empty"}
+		}
+	}
+
+	for _, slot := range program.Days {
+		if int(slot.Week) >= len(weeks) || slot.Day >= use_cases.DaysInWeek {
+			continue
+		}
+		day := &weeks[slot.Week].Days[slot.Day]
+		day.SlotID = slot.ID
+		if slot.TrainingID == nil {
+			day.Kind = "rest"
+			continue
+		}
+
+		day.Kind = "training"
+		day.TrainingID = *slot.TrainingID
+		index := slices.IndexFunc(trainings, func(t models.Training) bool {
+			return t.ID == *slot.TrainingID
+		})
+		if index != -1 {
+			day.Training = &ProgramTraining{
+				ID:      trainings[index].ID,
+				TitleEn: trainings[index].TitleEn,
+				TitleRu: trainings[index].TitleRu,
+				Draft:   trainings[index].Draft,
+				Version: trainings[index].Version,
+			}
+		}
+	}
+
+	return Program{
+		ID:         program.ID,
+		CreatedAt:  program.CreatedAt.Format("January 2, 2006"),
+		TitleEn:    program.TitleEn,
+		TitleRu:    program.TitleRu,
+		Draft:      program.Draft,
+		OwnerID:    program.OwnerID,
+		Visibility: program.Visibility,
+		Weeks:      weeks,
+	}
+}
+
+func (p *Presenter) Programs(programs []*models.Program) []Program {
+	arr := make([]Program, len(programs))
+	for i, program := range programs {
+		arr[i] = p.Program(program, []models.Training{})
+	}
+	return arr
+}
+
+type ScheduledSession struct {
+	ID         uint   `json:"id"`
+	TrainingID uint   `json:"trainingId"`
+	TitleEn    string `json:"titleEn"`
+	TitleRu    string `json:"titleRu"`
+	StartsAt   string `json:"startsAt"` // RFC 3339 in timezone
+	Timezone   string `json:"timezone"`
+	RRule      string `json:"rrule"`
+	Notes      string `json:"notes"`
+}
+
+func (p *Presenter) ScheduledSession(s *models.ScheduledSession) ScheduledSession {
+	return ScheduledSession{
+		ID:         s.ID,
+		TrainingID: s.TrainingID,
+		TitleEn:    s.Training.TitleEn,
+		TitleRu:    s.Training.TitleRu,
+		StartsAt:   inTimezone(s.StartsAt, s.Timezone).Format(time.RFC3339),
+		Timezone:   s.Timezone,
+		RRule:      s.RRule,
+		Notes:      s.Notes,
+	}
+}
+
+type Occurrence struct {
+	ScheduledSessionID uint   `json:"scheduledSessionId"`
+	TrainingID         uint   `json:"trainingId"`
+	TitleEn            string `json:"titleEn"`
+	TitleRu            string `json:"titleRu"`
+	StartsAt           string `json:"startsAt"` // RFC 3339 in timezone
+	EndsAt             string `json:"endsAt"`
+	Timezone           string `json:"timezone"`
+	Recurring          bool   `json:"recurring"`
+}
+
+func (p *Presenter) Occurrences(occurrences []use_cases.Occurrence) []Occurrence {
+	arr := make([]Occurrence, len(occurrences))
+	for i, o := range occurrences {
+		arr[i] = Occurrence{
+			ScheduledSessionID: o.Session.ID,
+			TrainingID:         o.Session.TrainingID,
+			TitleEn:            o.Session.Training.TitleEn,
+			TitleRu:            o.Session.Training.TitleRu,
+			StartsAt:           o.StartsAt.Format(time.RFC3339),
+			EndsAt:             o.EndsAt.Format(time.RFC3339),
+			Timezone:           o.Session.Timezone,
+			Recurring:          o.Session.RRule != "",
+		}
+	}
+	return arr
+}
+
+type FeedToken struct {
+	Token string `json:"token"`
+	URL   string `json:"url"` // path of iCalendar feed
+}
+
+func (p *Presenter) FeedToken(token string) FeedToken {
+	return FeedToken{Token: token, URL: fmt.Sprintf("/api/v1/schedule/feed/%s.ics", token)}
+}
+
+// ICalendar renders RFC 5545 feed, recurring sessions keep their RRULE so calendar apps expand them.
+// Every timezone of sessions but UTC gets VTIMEZONE, so local time of occurrences stays on daylight saving changes
+func (p *Presenter) ICalendar(sessions []models.ScheduledSession, durations map[uint]uint) string {
+	var b strings.Builder
+	line := func(s string) {
+		b.WriteString(foldICalLine(s))
+		b.WriteString("\r\n")
+	}
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//bf_me//schedule//EN")
+	line("CALSCALE:GREGORIAN")
+	line("X-WR-CALNAME:Trainings")
+
+	// timezones are described since the earliest session in them
+	since := make(map[string]time.Time)
+	timezones := make([]string, 0)
+	for _, s := range sessions {
+		first, ok := since[s.Timezone]
+		if !ok {
+			timezones = append(timezones, s.Timezone)
+		}
+		if !ok || s.StartsAt.Before(first) {
+			since[s.Timezone] = s.StartsAt
+		}
+	}
+	for _, timezone := range timezones {
+		if loc := icalLocation(timezone); loc != nil {
+			for _, l := range vTimezone(timezone, loc, since[timezone]) {
+				line(l)
+			}
+		}
+	}
+
+	for _, s := range sessions {
+		line("BEGIN:VEVENT")
+		line(fmt.Sprintf("UID:scheduled-session-%d@bf_me", s.ID))
+		line("DTSTAMP:" + s.UpdatedAt.UTC().Format("20060102T150405Z"))
+		if loc := icalLocation(s.Timezone); loc != nil {
+			line(fmt.Sprintf("DTSTART;TZID=%s:%s", s.Timezone, s.StartsAt.In(loc).Format("20060102T150405")))
+		} else {
+			line("DTSTART:" + s.StartsAt.UTC().Format("20060102T150405Z"))
+		}
+		line(fmt.Sprintf("DURATION:PT%dS", durations[s.TrainingID]))
+		if s.RRule != "" {
+			line("RRULE:" + s.RRule)
+		}
+		line("SUMMARY:" + escapeICalText(s.Training.TitleEn))
+		if s.Notes != "" {
+			line("DESCRIPTION:" + escapeICalText(s.Notes))
+		}
+		line("END:VEVENT")
+	}
+	line("END:VCALENDAR")
+	return b.String()
+}
+
+// icalLocation is location of timezone which needs VTIMEZONE, it is nil for UTC and unknown timezones
+func icalLocation(timezone string) *time.Location {
+	loc, err := time.LoadLocation(timezone)
+	if err != nil || loc == time.UTC {
+		return nil
+	}
+	return loc
+}
+
+// vTimezone describes offsets of loc from the start of since year for ten years after now.
+// Every change of offset is listed, as Go knows the changes but not the rules behind them
+func vTimezone(timezone string, loc *time.Location, since time.Time) []string {
+	from := time.Date(since.In(loc).Year(), time.January, 1, 0, 0, 0, 0, loc)
+	to := time.Date(max(time.Now().Year(), from.Year())+10, time.January, 1, 0, 0, 0, 0, loc)
+
+	lines := []string{"BEGIN:VTIMEZONE", "TZID:" + timezone}
+	observance := func(at time.Time, offsetFrom int) {
+		name, offsetTo := at.Zone()
+		kind := "STANDARD"
+		if at.IsDST() {
+			kind = "DAYLIGHT"
+		}
+		lines = append(lines,
+			"BEGIN:"+kind,
+			// local time of change is in the offset before it
+			"DTSTART:"+at.UTC().Add(time.Duration(offsetFrom)*time.Second).Format("20060102T150405"),
+			"TZOFFSETFROM:"+icalOffset(offsetFrom),
+			"TZOFFSETTO:"+icalOffset(offsetTo),
+			"TZNAME:"+name,
+			"END:"+kind,
+		)
+	}
+
+	_, offset := from.Zone()
+	observance(from, offset)
+	for day := from; day.Before(to); day = day.AddDate(0, 0, 1) {
+		next := day.AddDate(0, 0, 1)
+		if _, nextOffset := next.Zone(); nextOffset == offset {
+			continue
+		}
+		// the change is the first second of the day with the new offset
+		low, high := day.Unix(), next.Unix()
+		for high-low > 1 {
+			middle := (low + high) / 2
+			if _, o := time.Unix(middle, 0).In(loc).Zone(); o == offset {
+				low = middle
+			} else {
+				high = middle
+			}
+		}
+		change := time.Unix(high, 0).In(loc)
+		observance(change, offset)
+		_, offset = change.Zone()
+	}
+	return append(lines, "END:VTIMEZONE")
+}
+
+// icalOffset formats UTC offset in seconds like +0300
+func icalOffset(seconds int) string {
+	sign := "+"
+	if seconds < 0 {
+		sign, seconds = "-", -seconds
+	}
+	offset := fmt.Sprintf("%s%02d%02d", sign, seconds/3600, seconds/60%60)
+	if seconds%60 != 0 {
+		offset += fmt.Sprintf("%02d", seconds%60)
+	}
+	return offset
+}
+
+func inTimezone(t time.Time, timezone string) time.Time {
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return t.UTC()
+	}
+	return t.In(loc)
+}
+
+func escapeICalText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// foldICalLine splits lines longer than 75 octets without breaking utf-8 characters
+func foldICalLine(s string) string {
+	var b strings.Builder
+	width := 0
+	for _, r := range s {
+		size := utf8.RuneLen(r)
+		if width+size > 75 {
+			b.WriteString("\r\n ")
+			width = 1
+		}
+		b.WriteRune(r)
+		width += size
+	}
+	return b.String()
+}
+
+type RunState struct {
+	ID              uint     `json:"id"`
+	TrainingID      uint     `json:"trainingId"`
+	Status          string   `json:"status"`        // running, paused or finished
+	Position        int64    `json:"position"`      // milliseconds from the beginning of timeline
+	Elapsed         int64    `json:"elapsed"`       // milliseconds of active time, pauses and skips are not counted
+	TotalDuration   int64    `json:"totalDuration"` // milliseconds
+	SegmentIndex    int      `json:"segmentIndex"`  // -1 when finished
+	Segment         *Segment `json:"segment,omitempty"`
+	Remaining       int64    `json:"remaining"` // milliseconds till the end of segment
+	Next            *Segment `json:"next,omitempty"`
+	SkippedSegments []int64  `json:"skippedSegments"`
+	StartedAt       string   `json:"startedAt"`
+	FinishedAt      string   `json:"finishedAt,omitempty"`
+}
+
+func (p *Presenter) RunState(state use_cases.RunState) RunState {
+	skipped := []int64(state.Run.SkippedSegments)
+	if skipped == nil {
+		skipped = []int64{}
+	}
+	run := RunState{
+		ID:              state.Run.ID,
+		TrainingID:      state.Run.TrainingID,
+		Status:          state.Status,
+		Position:        state.Position,
+		Elapsed:         state.Elapsed,
+		TotalDuration:   state.TotalDuration,
+		SegmentIndex:    state.SegmentIndex,
+		Remaining:       state.Remaining,
+		SkippedSegments: skipped,
+		StartedAt:       state.Run.CreatedAt.Format(time.RFC3339),
+	}
+	if state.Segment != nil {
+		run.Segment = &p.Segments([]use_cases.Segment{*state.Segment})[0]
+	}
+	if state.Next != nil {
+		run.Next = &p.Segments([]use_cases.Segment{*state.Next})[0]
+	}
+	if state.Run.FinishedAt != nil {
+		run.FinishedAt = state.Run.FinishedAt.Format(time.RFC3339)
+	}
+	return run
+}
+
+type WorkoutLog struct {
+	ID         uint            `json:"id"`
+	TrainingID uint            `json:"trainingId"`
+	TitleEn    string          `json:"titleEn"`
+	TitleRu    string          `json:"titleRu"`
+	RunID      *uint           `json:"runId"`
+	StartedAt  string          `json:"startedAt"`
+	FinishedAt string          `json:"finishedAt"`
+	Duration   uint            `json:"duration"` // seconds
+	Notes      string          `json:"notes"`
+	Results    []WorkoutResult `json:"results"`
+}
+
+type WorkoutResult struct {
+	ID         uint     `json:"id"`
+	BlockID    uint     `json:"blockId"`
+	SlotID     uint     `json:"slotId"`
+	ExerciseID uint     `json:"exerciseId"`
+	Round      uint8    `json:"round"`
+	Side       string   `json:"side"`
+	Reps       *uint16  `json:"reps"`
+	Load       *float32 `json:"load"`
+	Skipped    bool     `json:"skipped"`
+	RPE        *uint8   `json:"rpe"`
+}
+
+func (p *Presenter) WorkoutLog(log *models.WorkoutLog) WorkoutLog {
+	results := make([]WorkoutResult, len(log.Results))
+	for i, r := range log.Results {
+		results[i] = WorkoutResult{
+			ID:         r.ID,
+			BlockID:    r.BlockID,
+			SlotID:     r.SlotID,
+			ExerciseID: r.ExerciseID,
+			Round:      r.Round,
+			Side:       r.Side,
+			Reps:       r.Reps,
+			Load:       r.Load,
+			Skipped:    r.Skipped,
+			RPE:        r.RPE,
+		}
+	}
+
+	return WorkoutLog{
+		ID:         log.ID,
+		TrainingID: log.TrainingID,
+		TitleEn:    log.Training.TitleEn,
+		TitleRu:    log.Training.TitleRu,
+		RunID:      log.RunID,
+		StartedAt:  log.StartedAt.Format(time.RFC3339),
+		FinishedAt: log.FinishedAt.Format(time.RFC3339),
+		Duration:   uint(log.FinishedAt.Sub(log.StartedAt).Seconds()),
+		Notes:      log.Notes,
+		Results:    results,
+	}
+}
+
+func (p *Presenter) WorkoutLogs(logs []models.WorkoutLog) []WorkoutLog {
+	arr := make([]WorkoutLog, len(logs))
+	for i := range logs {
+		arr[i] = p.WorkoutLog(&logs[i])
+	}
+	return arr
+}
+
+type Volume struct {
+	Period string        `json:"period"`
+	Points []VolumePoint `json:"points"`
+	Total  VolumePoint   `json:"total"`
+}
+
+type VolumePoint struct {
+	PeriodStart string  `json:"periodStart,omitempty"` // date in requested timezone
+	Workouts    uint    `json:"workouts"`
+	Duration    uint    `json:"duration"` // seconds
+	WorkTime    uint    `json:"workTime"` // seconds
+	Reps        uint    `json:"reps"`
+	Load        float64 `json:"load"` // kg
+	Skipped     uint    `json:"skipped"`
+}
+
+func (p *Presenter) Volume(period string, points []use_cases.VolumePoint) Volume {
+	volume := Volume{Period: period, Points: make([]VolumePoint, len(points))}
+	for i, point := range points {
+		volume.Points[i] = VolumePoint{
+			PeriodStart: point.PeriodStart.Format(time.DateOnly),
+			Workouts:    point.Workouts,
+			Duration:    point.Duration,
+			WorkTime:    point.WorkTime,
+			Reps:        point.Reps,
+			Load:        point.Load,
+			Skipped:     point.Skipped,
+		}
+		volume.Total.Workouts += point.Workouts
+		volume.Total.Duration += point.Duration
+		volume.Total.WorkTime += point.WorkTime
+		volume.Total.Reps += point.Reps
+		volume.Total.Load += point.Load
+		volume.Total.Skipped += point.Skipped
+	}
+	return volume
+}
+
+type Streaks struct {
+	Current Streak `json:"current"`
+	Longest Streak `json:"longest"`
+}
+
+type Streak struct {
+	From   string `json:"from,omitempty"` // date in requested timezone
+	To     string `json:"to,omitempty"`
+	Length uint   `json:"length"` // days
+}
+
+func (p *Presenter) Streaks(current, longest use_cases.Streak) Streaks {
+	return Streaks{Current: p.streak(current), Longest: p.streak(longest)}
+}
+
+func (p *Presenter) streak(s use_cases.Streak) Streak {
+	if s.Length == 0 {
+		return Streak{}
+	}
+	return Streak{From: s.From.Format(time.DateOnly), To: s.To.Format(time.DateOnly), Length: s.Length}
+}
+
+type Progression struct {
+	ExerciseID uint               `json:"exerciseId"`
+	Points     []ProgressionPoint `json:"points"`
+}
+
+type ProgressionPoint struct {
+	Day     string  `json:"day"` // date in requested timezone
+	Sets    uint    `json:"sets"`
+	Reps    uint    `json:"reps"`
+	MaxReps uint    `json:"maxReps"`
+	MaxLoad float64 `json:"maxLoad"` // kg
+	Load    float64 `json:"load"`    // kg
+}
+
+func (p *Presenter) Progression(exerciseID uint, points []use_cases.ProgressionPoint) Progression {
+	progression := Progression{ExerciseID: exerciseID, Points: make([]ProgressionPoint, len(points))}
+	for i, point := range points {
+		progression.Points[i] = ProgressionPoint{
+			Day:     point.Day.Format(time.DateOnly),
+			Sets:    point.Sets,
+			Reps:    point.Reps,
+			MaxReps: point.MaxReps,
+			MaxLoad: point.MaxLoad,
+			Load:    point.Load,
+		}
+	}
+	return progression
+}
+
+type MuscleGroupDistribution struct {
+	WorkTime     uint    `json:"workTime"` // seconds
+	MuscleGroups []Share `json:"muscleGroups"`
+}
+
+func (p *Presenter) MuscleGroupDistribution(shares []use_cases.Share, workTime uint) MuscleGroupDistribution {
+	return MuscleGroupDistribution{WorkTime: workTime, MuscleGroups: p.shares(shares, workTime)}
+}
+
+type User struct {
+	ID        uint    `json:"id"`
+	CreatedAt string  `json:"createdAt"`
+	Login     string  `json:"login"`
+	Email     *string `json:"email"`
+	Role      string  `json:"role"`
+	Disabled  bool    `json:"disabled"`
+}
+
+func (p *Presenter) User(user *models.User) User {
+	return User{
+		ID:        user.ID,
+		CreatedAt: user.CreatedAt.Format("January 2, 2006"),
+		Login:     user.Login,
+		Email:     user.Email,
+		Role:      user.Role,
+		Disabled:  user.Disabled,
+	}
+}
+
+func (p *Presenter) Users(users []models.User) []User {
+	arr := make([]User, len(users))
+	for i := range users {
+		arr[i] = p.User(&users[i])
+	}
+	return arr
+}
+
+type Sharing struct {
+	Visibility string `json:"visibility"`
+	UserIDs    []uint `json:"userIds"`
+}
+
+func (p *Presenter) Sharing(sharing use_cases.Sharing) Sharing {
+	return Sharing{Visibility: sharing.Visibility, UserIDs: sharing.UserIDs}
+}
+
+type Assignment struct {
+	ID           uint    `json:"id"`
+	CreatedAt    string  `json:"createdAt"` // RFC 3339, workouts are counted since it
+	CoachID      uint    `json:"coachId"`
+	CoachLogin   string  `json:"coachLogin"`
+	AthleteID    uint    `json:"athleteId"`
+	AthleteLogin string  `json:"athleteLogin"`
+	TrainingID   *uint   `json:"trainingId"`
+	ProgramID    *uint   `json:"programId"`
+	TitleEn      string  `json:"titleEn"`
+	TitleRu      string  `json:"titleRu"`
+	DueDate      string  `json:"dueDate"`
+	Notes        string  `json:"notes"`
+	Status       string  `json:"status"` // enum of ["pending", "overdue", "done"]
+	Required     uint    `json:"required"`
+	Done         uint    `json:"done"`
+	LastLogAt    *string `json:"lastLogAt"`
+}
+
+func (p *Presenter) Assignment(ap *use_cases.AssignmentProgress) Assignment {
+	a := ap.Assignment
+	assignment := Assignment{
+		ID:           a.ID,
+		CreatedAt:    a.CreatedAt.UTC().Format(time.RFC3339),
+		CoachID:      a.CoachID,
+		CoachLogin:   a.Coach.Login,
+		AthleteID:    a.AthleteID,
+		AthleteLogin: a.Athlete.Login,
+		TrainingID:   a.TrainingID,
+		ProgramID:    a.ProgramID,
+		DueDate:      a.DueDate.Format(time.DateOnly),
+		Notes:        a.Notes,
+		Status:       ap.Status(),
+		Required:     ap.Required,
+		Done:         ap.Done,
+	}
+	if a.Training != nil {
+		assignment.TitleEn, assignment.TitleRu = a.Training.TitleEn, a.Training.TitleRu
+	}
+	if a.Program != nil {
+		assignment.TitleEn, assignment.TitleRu = a.Program.TitleEn, a.Program.TitleRu
+	}
+	if ap.LastLogAt != nil {
+		lastLogAt := ap.LastLogAt.UTC().Format(time.RFC3339)
+		assignment.LastLogAt = &lastLogAt
+	}
+	return assignment
+}
+
+func (p *Presenter) Assignments(progress []use_cases.AssignmentProgress) []Assignment {
+	arr := make([]Assignment, len(progress))
+	for i := range progress {
+		arr[i] = p.Assignment(&progress[i])
+	}
+	return arr
+}
+
+type APIKey struct {
+	ID         uint     `json:"id"`
+	Name       string   `json:"name"`
+	UserID     uint     `json:"userId"`
+	UserLogin  string   `json:"userLogin"`
+	Prefix     string   `json:"prefix"`
+	Key        string   `json:"key,omitempty"` // only when key is issued
+	Scopes     []string `json:"scopes"`
+	CreatedAt  string   `json:"createdAt"` // RFC 3339
+	ExpiresAt  *string  `json:"expiresAt"`
+	LastUsedAt *string  `json:"lastUsedAt"`
+}
+
+func (p *Presenter) APIKey(key *models.APIKey) APIKey {
+	apiKey := APIKey{
+		ID:        key.ID,
+		Name:      key.Name,
+		UserID:    key.UserID,
+		UserLogin: key.User.Login,
+		Prefix:    key.Prefix,
+		Key:       key.Key,
+		Scopes:    []string(key.Scopes),
+		CreatedAt: key.CreatedAt.UTC().Format(time.RFC3339),
+	}
+	if apiKey.Scopes == nil {
+		apiKey.Scopes = []string{}
+	}
+	if key.ExpiresAt != nil {
+		expiresAt := key.ExpiresAt.UTC().Format(time.RFC3339)
+		apiKey.ExpiresAt = &expiresAt
+	}
+	if key.LastUsedAt != nil {
+		lastUsedAt := key.LastUsedAt.UTC().Format(time.RFC3339)
+		apiKey.LastUsedAt = &lastUsedAt
+	}
+	return apiKey
+}
+
+func (p *Presenter) APIKeys(keys []models.APIKey) []APIKey {
+	arr := make([]APIKey, len(keys))
+	for i := range keys {
+		arr[i] = p.APIKey(&keys[i])
+	}
+	return arr
+}
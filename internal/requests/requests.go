@@ -5,7 +5,7 @@ import (
 	"mime/multipart"
 )
 
-// @note Tips should be sent in form `str1,str2,str3`
+// @note Tips, TagIds, muscle groups and equipment should be sent in form `str1,str2,str3`
 type CreateExerciseRequest struct {
 	Exercise   *models.Exercise
 	TagIds     string
@@ -16,9 +16,14 @@ type CreateExerciseRequest struct {
 
 // @note Tips should be sent in form `str1,str2,str3`
 type UpdateExerciseRequestBody struct {
-	TitleEn string   `json:"titleEn"`
-	TitleRu string   `json:"titleRu"`
-	Tips    []string `json:"tips"`
+	TitleEn      string   `json:"titleEn"`
+	TitleRu      string   `json:"titleRu"`
+	Tips         []string `json:"tips"`
+	Unilateral   *bool    `json:"unilateral"`
+	MuscleGroups []string `json:"muscleGroups"`
+	Equipment    []string `json:"equipment"`
+	Difficulty   *uint8   `json:"difficulty"`
+	TagIDs       []uint   `json:"tagIds"`
 }
 
 type FilterExercisesRequestBody struct {
@@ -34,9 +39,14 @@ type CreateTagRequestBody struct {
 	TitleRu string `json:"titleRu"`
 }
 
+// @note Cookie makes login set HttpOnly session cookie instead of returning token,
+// state-changing requests with the cookie should send X-CSRF-Token header with the returned csrfToken.
+// DeviceID is optional random id which client generates once and keeps, login on the same device replaces its session
 type UserRequestBody struct {
 	Login    string `json:"login"`
 	Password string `json:"password"`
+	Cookie   bool   `json:"cookie"`
+	DeviceID string `json:"deviceId"`
 }
 
 type BlockRequestBody struct {
@@ -53,11 +63,207 @@ type FilterRequestBody struct {
 	Suggestion string `json:"suggestion,omitempty"`
 }
 
+// @note unilateral exercise is always added as left+right pair, Side chooses which one goes first
 type AddBlockExerciseRequestBody struct {
 	Side string `json:"side"` // undefined, left and right
 }
 
+type InsertBlockExerciseRequestBody struct {
+	Side     string `json:"side"`     // undefined, left and right
+	Position uint   `json:"position"` // starts from 0
+}
+
+// @note nil Side keeps current side, zero ExerciseID keeps current exercise
+type UpdateSlotRequestBody struct {
+	ExerciseID uint    `json:"exerciseId"`
+	Side       *string `json:"side"` // undefined, left and right
+}
+
+type MoveSlotRequestBody struct {
+	From uint `json:"from"` // current position, starts from 0
+	To   uint `json:"to"`   // new position, starts from 0
+}
+
+// @note Order lists current positions in the new order, e.g. [2,0,1] moves last slot to the top
+type ReorderSlotsRequestBody struct {
+	Order []uint `json:"order"`
+}
+
+// @note nil TransitionRest means default rest, zero Repeats means a single round
+type InsertTrainingBlockRequestBody struct {
+	Position       uint    `json:"position"`       // starts from 0
+	TransitionRest *uint16 `json:"transitionRest"` // seconds
+	Repeats        uint8   `json:"repeats"`
+}
+
+// @note nil fields keep current values
+type UpdateTrainingSlotRequestBody struct {
+	TransitionRest *uint16 `json:"transitionRest"` // seconds
+	Repeats        *uint8  `json:"repeats"`
+}
+
+// @note pool is ExerciseIDs if they are given, otherwise exercises with any of TagIDs or MuscleGroups.
+// nil Seed keeps pool order, AlternateSides makes every next unilateral pair start from the other side
+type AutofillBlockRequestBody struct {
+	TagIDs         []uint   `json:"tagIds"`
+	MuscleGroups   []string `json:"muscleGroups"`
+	ExerciseIDs    []uint   `json:"exerciseIds"`
+	Seed           *int64   `json:"seed"`
+	NoRepeats      bool     `json:"noRepeats"`
+	AlternateSides bool     `json:"alternateSides"`
+}
+
+type CloneTrainingRequestBody struct {
+	Deep bool `json:"deep"` // clone every block too
+}
+
 type TrainingRequestBody struct {
 	TitleEn string `json:"titleEn"`
 	TitleRu string `json:"titleRu"`
 }
+
+// @note zero Weeks keeps current value, days of removed weeks are removed too
+type ProgramRequestBody struct {
+	TitleEn string `json:"titleEn"`
+	TitleRu string `json:"titleRu"`
+	Weeks   uint8  `json:"weeks"`
+}
+
+// @note nil TrainingID makes the day a rest day
+type SetProgramDayRequestBody struct {
+	Week       uint8 `json:"week"` // starts from 0
+	Day        uint8 `json:"day"`  // from 0 (monday) to 6 (sunday)
+	TrainingID *uint `json:"trainingId"`
+}
+
+// @note nil Equipment allows any equipment, empty one allows only bodyweight exercises.
+// Zero Difficulty allows any difficulty, otherwise it is the highest allowed one
+type GenerateTrainingRequestBody struct {
+	TitleEn            string   `json:"titleEn"`
+	TitleRu            string   `json:"titleRu"`
+	Duration           uint16   `json:"duration"` // minutes
+	TagIDs             []uint   `json:"tagIds"`
+	MuscleGroups       []string `json:"muscleGroups"`
+	Equipment          []string `json:"equipment"`
+	Difficulty         uint8    `json:"difficulty"`
+	ExcludeExerciseIDs []uint   `json:"excludeExerciseIds"`
+}
+
+// @note Date is `2006-01-02` and Time is `15:04` in Timezone, empty fields keep current values on update.
+// RRule is RFC 5545 rule like `FREQ=WEEKLY;BYDAY=MO,TH`, empty one makes a single session
+type ScheduleRequestBody struct {
+	TrainingID uint    `json:"trainingId"`
+	Date       string  `json:"date"`
+	Time       string  `json:"time"`
+	Timezone   string  `json:"timezone"`
+	RRule      *string `json:"rrule"`
+	Notes      *string `json:"notes"`
+}
+
+// @note From and To are RFC 3339 times, range is up to a year long
+type ScheduleRangeRequestBody struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type StartRunRequestBody struct {
+	TrainingID uint `json:"trainingId"`
+}
+
+// @note StartedAt and FinishedAt are RFC 3339 times. Log made of RunID takes its times,
+// and its results are filled from the run when Results are empty
+type WorkoutLogRequestBody struct {
+	TrainingID uint                   `json:"trainingId"`
+	RunID      *uint                  `json:"runId"`
+	StartedAt  string                 `json:"startedAt"`
+	FinishedAt string                 `json:"finishedAt"`
+	Notes      *string                `json:"notes"`
+	Results    []WorkoutResultRequest `json:"results"`
+}
+
+type WorkoutResultRequest struct {
+	SlotID  uint     `json:"slotId"`
+	Round   uint8    `json:"round"` // starts from 1, zero is the first round
+	Reps    *uint16  `json:"reps"`
+	Load    *float32 `json:"load"` // kg
+	Skipped bool     `json:"skipped"`
+	RPE     *uint8   `json:"rpe"` // from 1 to 10
+}
+
+// @note From and To are RFC 3339 times, empty ones are not limited
+type FilterWorkoutLogsRequestBody struct {
+	From       string `json:"from"`
+	To         string `json:"to"`
+	TrainingID uint   `json:"trainingId"`
+}
+
+// @note From and To are RFC 3339 times, empty ones are not limited. Days and periods are counted in Timezone,
+// Period is enum of ["week", "month"] and ExerciseID is required for progression only
+type AnalyticsRequestBody struct {
+	From       string `json:"from"`
+	To         string `json:"to"`
+	Timezone   string `json:"timezone"`
+	Period     string `json:"period"`
+	ExerciseID uint   `json:"exerciseId"`
+}
+
+// @note Role is enum of ["admin", "coach", "athlete"], empty one is athlete
+type CreateUserRequestBody struct {
+	Login    string `json:"login"`
+	Password string `json:"password"`
+	Email    string `json:"email"`
+	Role     string `json:"role"`
+}
+
+type UserRoleRequestBody struct {
+	Role string `json:"role"`
+}
+
+// @note Visibility is enum of ["private", "shared", "organization"], UserIDs are users which see shared content
+type SharingRequestBody struct {
+	Visibility string `json:"visibility"`
+	UserIDs    []uint `json:"userIds"`
+}
+
+// @note Exactly one of TrainingID and ProgramID should be set, DueDate is like 2006-01-02.
+// On update athlete and content are kept and empty fields keep current values
+type AssignmentRequestBody struct {
+	AthleteID  uint    `json:"athleteId"`
+	TrainingID uint    `json:"trainingId"`
+	ProgramID  uint    `json:"programId"`
+	DueDate    string  `json:"dueDate"`
+	Notes      *string `json:"notes"`
+}
+
+// @note From and To limit due dates and are like 2006-01-02, empty ones are not limited.
+// Status is enum of ["", "pending", "overdue", "done"], AthleteID is used by dashboard only
+type FilterAssignmentsRequestBody struct {
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Status    string `json:"status"`
+	AthleteID uint   `json:"athleteId"`
+}
+
+// @note Scopes are "read", "write" or "<resource>:write" like "exercises:write", write scope of resource
+// lets read it too. ExpiresAt is RFC 3339 time, empty key does not expire
+type APIKeyRequestBody struct {
+	Name      string   `json:"name"`
+	UserID    uint     `json:"userId"`
+	Scopes    []string `json:"scopes"`
+	ExpiresAt string   `json:"expiresAt"`
+}
+
+type ChangePasswordRequestBody struct {
+	CurrentPassword string `json:"currentPassword"`
+	NewPassword     string `json:"newPassword"`
+}
+
+type RequestPasswordResetRequestBody struct {
+	Login string `json:"login"`
+}
+
+// @note Token is the one from password reset mail, it can be used once
+type ResetPasswordRequestBody struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"newPassword"`
+}
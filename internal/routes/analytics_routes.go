@@ -0,0 +1,92 @@
+package routes
+
+import (
+	"bf_me/internal/presenters"
+	"bf_me/internal/requests"
+	"bf_me/internal/storage"
+	"bf_me/internal/use_cases"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+type AnalyticsRouter struct {
+	presenter   *presenters.Presenter
+	useCase     *use_cases.AnalyticsUseCase
+	authUseCase *use_cases.SessionsUseCase
+}
+
+func newAnalyticsRouter(st *storage.Storage) *AnalyticsRouter {
+	return &AnalyticsRouter{
+		presenter:   presenters.NewPresenter(),
+		useCase:     use_cases.NewAnalyticsUseCase(st),
+		authUseCase: use_cases.NewSessionsUseCase(st),
+	}
+}
+
+func RegisterAnalyticsRoutes(mux *http.ServeMux, st *storage.Storage) {
+	router := newAnalyticsRouter(st)
+	// report is enum of ["volume", "streaks", "progression", "muscle_groups"]
+	mux.HandleFunc("/api/v1/analytics/{report}", AuthMiddleware(router.authUseCase, router.report))
+}
+
+func (router *AnalyticsRouter) report(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "No such endpoint", http.StatusNotFound)
+		return
+	}
+
+	var req requests.AnalyticsRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	userID := currentUser(r).ID
+	var data any
+	switch r.PathValue("report") {
+	case "volume":
+		points, err := router.useCase.Volume(userID, &req)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		data = router.presenter.Volume(req.Period, points)
+	case "streaks":
+		current, longest, err := router.useCase.Streaks(userID, &req)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		data = router.presenter.Streaks(current, longest)
+	case "progression":
+		points, err := router.useCase.Progression(userID, &req)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		data = router.presenter.Progression(req.ExerciseID, points)
+	case "muscle_groups":
+		shares, workTime, err := router.useCase.MuscleGroups(userID, &req)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		data = router.presenter.MuscleGroupDistribution(shares, workTime)
+	default:
+		http.Error(w, "No such endpoint", http.StatusNotFound)
+		return
+	}
+
+	byteData, err := json.Marshal(data)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("json encoding err: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err = w.Write(byteData); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,124 @@
+package routes
+
+import (
+	"bf_me/internal/presenters"
+	"bf_me/internal/requests"
+	"bf_me/internal/storage"
+	"bf_me/internal/use_cases"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"gorm.io/gorm"
+)
+
+type APIKeysRouter struct {
+	presenter   *presenters.Presenter
+	useCase     *use_cases.APIKeysUseCase
+	authUseCase *use_cases.SessionsUseCase
+}
+
+func newAPIKeysRouter(st *storage.Storage) *APIKeysRouter {
+	return &APIKeysRouter{
+		presenter:   presenters.NewPresenter(),
+		useCase:     use_cases.NewAPIKeysUseCase(st),
+		authUseCase: use_cases.NewSessionsUseCase(st),
+	}
+}
+
+func RegisterAPIKeysRoutes(mux *http.ServeMux, st *storage.Storage) {
+	router := newAPIKeysRouter(st)
+	mux.HandleFunc("/api/v1/api_keys/create", AuthMiddleware(router.authUseCase, router.create, use_cases.RoleAdmin))
+	mux.HandleFunc("/api/v1/api_keys/list", AuthMiddleware(router.authUseCase, router.list, use_cases.RoleAdmin))
+	mux.HandleFunc("/api/v1/api_keys/{id}", AuthMiddleware(router.authUseCase, router.revoke, use_cases.RoleAdmin))
+}
+
+// create issues key, the response is the only place where the raw key is shown
+func (router *APIKeysRouter) create(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "No such endpoint", http.StatusNotFound)
+		return
+	}
+
+	var req requests.APIKeyRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	result, err := router.useCase.Create(&req)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	byteData, err := json.Marshal(router.presenter.APIKey(result))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("json encoding err: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+
+	if _, err = w.Write(byteData); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (router *APIKeysRouter) list(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "No such endpoint", http.StatusNotFound)
+		return
+	}
+
+	result, err := router.useCase.List()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	byteData, err := json.Marshal(router.presenter.APIKeys(result))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("json encoding err: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err = w.Write(byteData); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (router *APIKeysRouter) revoke(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "No such endpoint", http.StatusNotFound)
+		return
+	}
+
+	idInt, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, fmt.Errorf("invalid id provided: %s", err).Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	err = router.useCase.Revoke(idInt)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	if _, err = w.Write([]byte("successfully deleted")); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,202 @@
+package routes
+
+import (
+	"bf_me/internal/models"
+	"bf_me/internal/presenters"
+	"bf_me/internal/requests"
+	"bf_me/internal/storage"
+	"bf_me/internal/use_cases"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"gorm.io/gorm"
+)
+
+type AssignmentsRouter struct {
+	presenter   *presenters.Presenter
+	useCase     *use_cases.AssignmentsUseCase
+	authUseCase *use_cases.SessionsUseCase
+}
+
+func newAssignmentsRouter(st *storage.Storage) *AssignmentsRouter {
+	return &AssignmentsRouter{
+		presenter:   presenters.NewPresenter(),
+		useCase:     use_cases.NewAssignmentsUseCase(st),
+		authUseCase: use_cases.NewSessionsUseCase(st),
+	}
+}
+
+func RegisterAssignmentsRoutes(mux *http.ServeMux, st *storage.Storage) {
+	router := newAssignmentsRouter(st)
+	mux.HandleFunc("/api/v1/assignments/create", AuthMiddleware(router.authUseCase, router.create, editors...))
+	mux.HandleFunc("/api/v1/assignments/mine", AuthMiddleware(router.authUseCase, router.mine))
+	mux.HandleFunc("/api/v1/assignments/dashboard", AuthMiddleware(router.authUseCase, router.dashboard, editors...))
+	mux.HandleFunc("/api/v1/assignments/{id}", AuthMiddleware(router.authUseCase, router.mux))
+}
+
+func (router *AssignmentsRouter) create(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "No such endpoint", http.StatusNotFound)
+		return
+	}
+
+	var req requests.AssignmentRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	result, err := router.useCase.Create(currentUser(r), &req)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	router.writeAssignment(w, result, http.StatusCreated)
+}
+
+// mine is the feed of assignments given to the current user
+func (router *AssignmentsRouter) mine(w http.ResponseWriter, r *http.Request) {
+	router.list(w, r, router.useCase.Mine)
+}
+
+// dashboard is completion of assignments given by the current coach
+func (router *AssignmentsRouter) dashboard(w http.ResponseWriter, r *http.Request) {
+	router.list(w, r, router.useCase.Dashboard)
+}
+
+func (router *AssignmentsRouter) list(w http.ResponseWriter, r *http.Request,
+	find func(*models.User, *requests.FilterAssignmentsRequestBody) ([]use_cases.AssignmentProgress, error)) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "No such endpoint", http.StatusNotFound)
+		return
+	}
+
+	var req requests.FilterAssignmentsRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	result, err := find(currentUser(r), &req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	byteData, err := json.Marshal(router.presenter.Assignments(result))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("json encoding err: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err = w.Write(byteData); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (router *AssignmentsRouter) mux(w http.ResponseWriter, r *http.Request) {
+	idInt, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, fmt.Errorf("invalid id provided: %s", err).Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		router.get(idInt, w, r)
+		return
+	}
+	if !hasRole(r, editors...) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	if r.Method == http.MethodPost {
+		router.update(idInt, w, r)
+		return
+	}
+	if r.Method == http.MethodDelete {
+		router.delete(idInt, w, r)
+		return
+	}
+}
+
+func (router *AssignmentsRouter) get(id int, w http.ResponseWriter, r *http.Request) {
+	result, err := router.useCase.Find(currentUser(r), id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	router.writeAssignment(w, result, http.StatusOK)
+}
+
+func (router *AssignmentsRouter) update(id int, w http.ResponseWriter, r *http.Request) {
+	var req requests.AssignmentRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	result, err := router.useCase.Update(currentUser(r), id, &req)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, use_cases.ErrNotOwner) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	router.writeAssignment(w, result, http.StatusOK)
+}
+
+func (router *AssignmentsRouter) delete(id int, w http.ResponseWriter, r *http.Request) {
+	err := router.useCase.Delete(currentUser(r), id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, use_cases.ErrNotOwner) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	if _, err = w.Write([]byte("successfully deleted")); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (router *AssignmentsRouter) writeAssignment(w http.ResponseWriter, assignment *use_cases.AssignmentProgress, status int) {
+	byteData, err := json.Marshal(router.presenter.Assignment(assignment))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("json encoding err: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if _, err = w.Write(byteData); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
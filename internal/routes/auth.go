@@ -1,25 +1,205 @@
 package routes
 
 import (
+	"bf_me/internal/models"
+	"bf_me/internal/services"
 	"bf_me/internal/use_cases"
+	"crypto/subtle"
+	"fmt"
+	"net"
 	"net/http"
+	"slices"
 	"strings"
 )
 
-func AuthMiddleware(uc *use_cases.SessionsUseCase, next http.HandlerFunc) http.HandlerFunc {
+// realm names the protected space in WWW-Authenticate challenges
+const realm = "bf_me"
+
+// legacyTokenPrefix was sent by clients before RFC 6750 parsing, it is accepted during transition
+const legacyTokenPrefix = "token="
+
+// cookie session mode keeps token in HttpOnly cookie, CSRF token is readable by the page
+// and should be sent back in csrfHeader with every state-changing request.
+// Cookies are SameSite=Strict, so the page should be on the same site as API and listed in allowed origins
+const (
+	sessionCookie = "bf_me_session"
+	csrfCookie    = "bf_me_csrf"
+	csrfHeader    = "X-CSRF-Token"
+)
+
+// API key scopes: keys can not manage sessions, keys and passwords, POST endpoints which only read
+// and resources which are read only need read scope
+var (
+	keylessResources = []string{"sessions", "logout", "api_keys", "password"}
+	readResources    = []string{"analytics"}
+	readActions      = []string{"list", "range", "dashboard", "mine"}
+)
+
+// editors are roles which can change exercises, tags, blocks, trainings and programs
+var editors = []string{use_cases.RoleAdmin, use_cases.RoleCoach}
+
+// AuthMiddleware lets request through only with a valid session or API key, when roles are given user should have one of them.
+// Token is taken from Authorization header or from session cookie, the cookie needs CSRF token for state-changing methods.
+// API key is also limited by its scopes. Missing or invalid credentials are answered with 401 and a challenge,
+// a wrong role, scope or CSRF token with 403
+func AuthMiddleware(uc *use_cases.SessionsUseCase, next http.HandlerFunc, roles ...string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		bearerToken := r.Header.Get("Authorization")
-		if bearerToken == "" {
-			http.Error(w, "Unauthorized", http.StatusForbidden)
+		token, ok := bearerToken(r)
+		if ok && strings.HasPrefix(token, use_cases.APIKeyPrefix) {
+			key, err := uc.FindAPIKey(token)
+			if key == nil || err != nil {
+				unauthorized(w, "the API key is invalid or expired")
+				return
+			}
+			resource, write := requestScope(r)
+			if slices.Contains(keylessResources, resource) || !use_cases.APIKeyAllows(key, resource, write) ||
+				len(roles) != 0 && !slices.Contains(roles, key.User.Role) {
+				forbidden(w)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(use_cases.WithAPIKey(r.Context(), key)))
 			return
 		}
-		sessionId := strings.TrimPrefix(bearerToken, "Bearer token=")
-		session, err := uc.Find(sessionId)
 
-		if session != nil && err == nil {
-			next.ServeHTTP(w, r)
+		fromCookie := false
+		if !ok {
+			token, ok = cookieToken(r)
+			fromCookie = ok
+		}
+		if !ok {
+			unauthorized(w, "")
+			return
+		}
+		session, err := uc.Find(token)
+		if session == nil || err != nil {
+			unauthorized(w, "the access token is invalid or expired")
+			return
+		}
+		if fromCookie && !validCSRF(r, token) {
+			http.Error(w, "invalid CSRF token", http.StatusForbidden)
 			return
 		}
-		http.Error(w, "Unauthorized", http.StatusForbidden)
+		if len(roles) != 0 && !slices.Contains(roles, session.User.Role) {
+			forbidden(w)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(use_cases.WithSession(r.Context(), session)))
+	}
+}
+
+// requestScope returns API resource of request, like "exercises" of /api/v1/exercises/1,
+// and whether request changes it. Safe methods and POST endpoints of readActions only read
+func requestScope(r *http.Request) (string, bool) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/v1/"), "/")
+	resource := parts[0]
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return resource, false
+	}
+	if slices.Contains(readResources, resource) || slices.Contains(readActions, parts[len(parts)-1]) {
+		return resource, false
+	}
+	return resource, true
+}
+
+// bearerToken parses "Authorization: Bearer <token>" of RFC 6750, scheme is case-insensitive.
+// Legacy "Bearer token=<token>" is accepted too
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, found := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimPrefix(strings.TrimSpace(token), legacyTokenPrefix)
+	if token == "" || strings.ContainsAny(token, " \t") {
+		return "", false
+	}
+	return token, true
+}
+
+func cookieToken(r *http.Request) (string, bool) {
+	cookie, err := r.Cookie(sessionCookie)
+	if err != nil || cookie.Value == "" {
+		return "", false
+	}
+	return cookie.Value, true
+}
+
+// validCSRF lets safe methods through, others should send CSRF token of the session
+func validCSRF(r *http.Request, token string) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	expected := services.CSRFToken(token)
+	return subtle.ConstantTimeCompare([]byte(r.Header.Get(csrfHeader)), []byte(expected)) == 1
+}
+
+func setSessionCookies(w http.ResponseWriter, session *models.Session) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    session.Token,
+		Path:     "/api/",
+		Expires:  session.ExpiresAt,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteStrictMode,
+	})
+	http.SetCookie(w, &http.Cookie{
+		Name:     csrfCookie,
+		Value:    services.CSRFToken(session.Token),
+		Path:     "/",
+		Expires:  session.ExpiresAt,
+		Secure:   true,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+func clearSessionCookies(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{Name: sessionCookie, Path: "/api/", MaxAge: -1, HttpOnly: true, Secure: true, SameSite: http.SameSiteStrictMode})
+	http.SetCookie(w, &http.Cookie{Name: csrfCookie, Path: "/", MaxAge: -1, Secure: true, SameSite: http.SameSiteStrictMode})
+}
+
+// forbidden answers 403 to authenticated user which has no access
+func forbidden(w http.ResponseWriter) {
+	w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm=%q, error="insufficient_scope"`, realm))
+	http.Error(w, "Forbidden", http.StatusForbidden)
+}
+
+// unauthorized answers 401 with a challenge, description is empty when there were no credentials at all
+func unauthorized(w http.ResponseWriter, description string) {
+	challenge := fmt.Sprintf("Bearer realm=%q", realm)
+	if description != "" {
+		challenge += fmt.Sprintf(`, error="invalid_token", error_description=%q`, description)
+	}
+	w.Header().Set("WWW-Authenticate", challenge)
+	http.Error(w, "Unauthorized", http.StatusUnauthorized)
+}
+
+// currentSession is the session put by AuthMiddleware, it is nil for requests with API key
+func currentSession(r *http.Request) *models.Session {
+	return use_cases.SessionFromContext(r.Context())
+}
+
+// hasRole checks role of current user for handlers which serve several methods with different access
+func hasRole(r *http.Request, roles ...string) bool {
+	user := currentUser(r)
+	return user != nil && slices.Contains(roles, user.Role)
+}
+
+// currentUser is the user of session or API key put by AuthMiddleware, handlers behind it always have one
+func currentUser(r *http.Request) *models.User {
+	return use_cases.UserFromContext(r.Context())
+}
+
+// clientIP is the address of client for session labels, it trusts proxy headers as it is not used for checks
+func clientIP(r *http.Request) string {
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		first, _, _ := strings.Cut(forwarded, ",")
+		return strings.TrimSpace(first)
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
 	}
+	return host
 }
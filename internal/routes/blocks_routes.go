@@ -32,12 +32,19 @@ func newBlocksRouter(st *storage.Storage) *BlocksRouter {
 
 func RegisterBlocksRoutes(mux *http.ServeMux, st *storage.Storage) {
 	router := newBlocksRouter(st)
-	mux.HandleFunc("/api/v1/blocks/create", AuthMiddleware(router.authUseCase, router.create))
+	mux.HandleFunc("/api/v1/blocks/create", AuthMiddleware(router.authUseCase, router.create, editors...))
 	mux.HandleFunc("/api/v1/blocks/list", AuthMiddleware(router.authUseCase, router.list))
 
-	// action is enum of ["add", "remove"]
-	mux.HandleFunc("/api/v1/blocks/{block_id}/{action}/exercise/{exercise_id}", AuthMiddleware(router.authUseCase, router.handleExercise))
-	mux.HandleFunc("/api/v1/blocks/{id}/toggle_draft", AuthMiddleware(router.authUseCase, router.toggleDraft))
+	// action is enum of ["add", "insert", "remove"]
+	mux.HandleFunc("/api/v1/blocks/{block_id}/{action}/exercise/{exercise_id}", AuthMiddleware(router.authUseCase, router.handleExercise, editors...))
+	mux.HandleFunc("/api/v1/blocks/{block_id}/slots/{slot_id}", AuthMiddleware(router.authUseCase, router.handleSlot, editors...))
+	mux.HandleFunc("/api/v1/blocks/{id}/move", AuthMiddleware(router.authUseCase, router.moveExercise, editors...))
+	mux.HandleFunc("/api/v1/blocks/{id}/reorder", AuthMiddleware(router.authUseCase, router.reorderExercises, editors...))
+	mux.HandleFunc("/api/v1/blocks/{id}/autofill", AuthMiddleware(router.authUseCase, router.autofill, editors...))
+	mux.HandleFunc("/api/v1/blocks/{id}/toggle_draft", AuthMiddleware(router.authUseCase, router.toggleDraft, editors...))
+	mux.HandleFunc("/api/v1/blocks/{id}/validate", AuthMiddleware(router.authUseCase, router.validate))
+	mux.HandleFunc("/api/v1/blocks/{id}/timeline", AuthMiddleware(router.authUseCase, router.timeline))
+	mux.HandleFunc("/api/v1/blocks/{id}/clone", AuthMiddleware(router.authUseCase, router.clone, editors...))
 	mux.HandleFunc("/api/v1/blocks/{id}", AuthMiddleware(router.authUseCase, router.mux))
 }
 
@@ -53,7 +60,7 @@ func (router *BlocksRouter) create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := router.useCase.Create(&req)
+	result, err := router.useCase.Create(currentUser(r), &req)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
 		return
@@ -84,7 +91,7 @@ func (router *BlocksRouter) list(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := router.useCase.List(&req)
+	result, err := router.useCase.List(currentUser(r), &req)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
 		return
@@ -112,7 +119,7 @@ func (router *BlocksRouter) handleExercise(w http.ResponseWriter, r *http.Reques
 	}
 
 	action := r.PathValue("action")
-	if slices.Contains([]string{"add", "remove"}, action) == false {
+	if slices.Contains([]string{"add", "insert", "remove"}, action) == false {
 		http.Error(w, "No such endpoint", http.StatusNotFound)
 		return
 	}
@@ -137,15 +144,26 @@ func (router *BlocksRouter) handleExercise(w http.ResponseWriter, r *http.Reques
 			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
 			return
 		}
-		block, err = router.useCase.AddBlockExercise(uint(blockID), uint(exerciseID), &req)
+		block, err = router.useCase.AddBlockExercise(currentUser(r), uint(blockID), uint(exerciseID), &req)
+	} else if action == "insert" {
+		req := requests.InsertBlockExerciseRequestBody{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		block, err = router.useCase.InsertBlockExercise(currentUser(r), uint(blockID), uint(exerciseID), &req)
 	} else {
-		block, err = router.useCase.RemoveBlockExercise(uint(blockID), uint(exerciseID))
+		block, err = router.useCase.RemoveBlockExercise(currentUser(r), uint(blockID), uint(exerciseID))
 	}
 
 	if errors.Is(err, gorm.ErrRecordNotFound) {
 		http.Error(w, err.Error(), http.StatusNotFound)
 		return
 	}
+	if errors.Is(err, use_cases.ErrNotOwner) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
 		return
@@ -166,6 +184,151 @@ func (router *BlocksRouter) handleExercise(w http.ResponseWriter, r *http.Reques
 	}
 }
 
+func (router *BlocksRouter) handleSlot(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodDelete {
+		http.Error(w, "No such endpoint", http.StatusNotFound)
+		return
+	}
+
+	blockID, err := strconv.Atoi(r.PathValue("block_id"))
+	if err != nil {
+		http.Error(w, fmt.Errorf("invalid id provided: %s", err).Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	slotID, err := strconv.Atoi(r.PathValue("slot_id"))
+	if err != nil {
+		http.Error(w, fmt.Errorf("invalid id provided: %s", err).Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	var block models.Block
+	if r.Method == http.MethodPost {
+		var req requests.UpdateSlotRequestBody
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		block, err = router.useCase.UpdateBlockSlot(currentUser(r), uint(blockID), uint(slotID), &req)
+	} else {
+		block, err = router.useCase.RemoveBlockSlot(currentUser(r), uint(blockID), uint(slotID))
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, use_cases.ErrNotOwner) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	byteData, err := json.Marshal(router.presenter.Block(block))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("json encoding err: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err = w.Write(byteData); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (router *BlocksRouter) moveExercise(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "No such endpoint", http.StatusNotFound)
+		return
+	}
+
+	idInt, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, fmt.Errorf("invalid id provided: %s", err).Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	var req requests.MoveSlotRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	result, err := router.useCase.MoveBlockExercise(currentUser(r), uint(idInt), &req)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, use_cases.ErrNotOwner) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	byteData, err := json.Marshal(router.presenter.Block(result))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("json encoding err: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err = w.Write(byteData); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (router *BlocksRouter) reorderExercises(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "No such endpoint", http.StatusNotFound)
+		return
+	}
+
+	idInt, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, fmt.Errorf("invalid id provided: %s", err).Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	var req requests.ReorderSlotsRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	result, err := router.useCase.ReorderBlockExercises(currentUser(r), uint(idInt), &req)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, use_cases.ErrNotOwner) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	byteData, err := json.Marshal(router.presenter.Block(result))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("json encoding err: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err = w.Write(byteData); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
 func (router *BlocksRouter) mux(w http.ResponseWriter, r *http.Request) {
 	idInt, err := strconv.Atoi(r.PathValue("id"))
 	if err != nil {
@@ -177,6 +340,10 @@ func (router *BlocksRouter) mux(w http.ResponseWriter, r *http.Request) {
 		router.get(idInt, w, r)
 		return
 	}
+	if !hasRole(r, editors...) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
 	if r.Method == http.MethodPost {
 		router.update(idInt, w, r)
 		return
@@ -199,11 +366,66 @@ func (router *BlocksRouter) toggleDraft(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	result, err := router.useCase.ToggleDraft(idInt)
+	result, err := router.useCase.ToggleDraft(currentUser(r), idInt)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	var notReady *use_cases.NotReadyError
+	if errors.As(err, &notReady) {
+		router.writeValidation(w, notReady.Issues, http.StatusUnprocessableEntity)
+		return
+	}
+	if errors.Is(err, use_cases.ErrNotOwner) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	byteData, err := json.Marshal(router.presenter.Block(result))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("json encoding err: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err = w.Write(byteData); err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	}
+
+}
+
+func (router *BlocksRouter) autofill(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "No such endpoint", http.StatusNotFound)
+		return
+	}
+
+	idInt, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, fmt.Errorf("invalid id provided: %s", err).Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	var req requests.AutofillBlockRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	result, err := router.useCase.Autofill(currentUser(r), uint(idInt), &req)
 	if errors.Is(err, gorm.ErrRecordNotFound) {
 		http.Error(w, err.Error(), http.StatusNotFound)
 		return
 	}
+	if errors.Is(err, use_cases.ErrNotOwner) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
 		return
@@ -218,13 +440,121 @@ func (router *BlocksRouter) toggleDraft(w http.ResponseWriter, r *http.Request)
 	w.WriteHeader(http.StatusOK)
 
 	if _, err = w.Write(byteData); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (router *BlocksRouter) clone(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "No such endpoint", http.StatusNotFound)
+		return
+	}
+
+	idInt, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, fmt.Errorf("invalid id provided: %s", err).Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	result, err := router.useCase.Clone(currentUser(r), idInt)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
 		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
 	}
 
+	byteData, err := json.Marshal(router.presenter.Block(result))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("json encoding err: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+
+	if _, err = w.Write(byteData); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
 }
 
-func (router *BlocksRouter) get(id int, w http.ResponseWriter, _ *http.Request) {
-	result, err := router.useCase.Find(id)
+func (router *BlocksRouter) timeline(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "No such endpoint", http.StatusNotFound)
+		return
+	}
+
+	idInt, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, fmt.Errorf("invalid id provided: %s", err).Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	block, segments, err := router.useCase.Timeline(currentUser(r), idInt)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	byteData, err := json.Marshal(router.presenter.Timeline(block.ID, segments))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("json encoding err: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err = w.Write(byteData); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (router *BlocksRouter) validate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "No such endpoint", http.StatusNotFound)
+		return
+	}
+
+	idInt, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, fmt.Errorf("invalid id provided: %s", err).Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	issues, err := router.useCase.Validate(currentUser(r), idInt)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	router.writeValidation(w, issues, http.StatusOK)
+}
+
+func (router *BlocksRouter) writeValidation(w http.ResponseWriter, issues []use_cases.Issue, status int) {
+	byteData, err := json.Marshal(router.presenter.Validation(issues))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("json encoding err: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if _, err = w.Write(byteData); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (router *BlocksRouter) get(id int, w http.ResponseWriter, r *http.Request) {
+	result, err := router.useCase.Find(currentUser(r), id)
 	if errors.Is(err, gorm.ErrRecordNotFound) {
 		http.Error(w, err.Error(), http.StatusNotFound)
 		return
@@ -254,7 +584,11 @@ func (router *BlocksRouter) update(id int, w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	result, err := router.useCase.Update(id, &req)
+	result, err := router.useCase.Update(currentUser(r), id, &req)
+	if errors.Is(err, use_cases.ErrNotOwner) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
 		return
@@ -273,8 +607,12 @@ func (router *BlocksRouter) update(id int, w http.ResponseWriter, r *http.Reques
 	}
 }
 
-func (router *BlocksRouter) delete(id int, w http.ResponseWriter, _ *http.Request) {
-	err := router.useCase.Delete(id)
+func (router *BlocksRouter) delete(id int, w http.ResponseWriter, r *http.Request) {
+	err := router.useCase.Delete(currentUser(r), id)
+	if errors.Is(err, use_cases.ErrNotOwner) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
 		return
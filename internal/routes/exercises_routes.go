@@ -32,7 +32,7 @@ func newExercisesRouter(st *storage.Storage) *ExercisesRouter {
 
 func RegisterExercisesRoutes(mux *http.ServeMux, st *storage.Storage) {
 	router := newExercisesRouter(st)
-	mux.HandleFunc("/api/v1/exercises/create", AuthMiddleware(router.authUseCase, router.create))
+	mux.HandleFunc("/api/v1/exercises/create", AuthMiddleware(router.authUseCase, router.create, editors...))
 	mux.HandleFunc("/api/v1/exercises/list", AuthMiddleware(router.authUseCase, router.list))
 	mux.HandleFunc("/api/v1/exercises/{id}", AuthMiddleware(router.authUseCase, router.mux))
 }
@@ -49,7 +49,7 @@ func (router *ExercisesRouter) list(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := router.useCase.List(&req)
+	result, err := router.useCase.List(currentUser(r), &req)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
 		return
@@ -94,21 +94,38 @@ func (router *ExercisesRouter) create(w http.ResponseWriter, r *http.Request) {
 	}()
 
 	exercise := &models.Exercise{
-		TitleEn: r.FormValue("titleEn"),
-		TitleRu: r.FormValue("titleRu"),
+		TitleEn:    r.FormValue("titleEn"),
+		TitleRu:    r.FormValue("titleRu"),
+		Unilateral: r.FormValue("unilateral") == "true",
 	}
 	tips := r.FormValue("tips")
 	if tips != "" {
 		exercise.Tips = strings.Split(r.FormValue("tips"), ",")
 
 	}
+	muscleGroups := r.FormValue("muscleGroups")
+	if muscleGroups != "" {
+		exercise.MuscleGroups = strings.Split(muscleGroups, ",")
+	}
+	equipment := r.FormValue("equipment")
+	if equipment != "" {
+		exercise.Equipment = strings.Split(equipment, ",")
+	}
+	if difficulty := r.FormValue("difficulty"); difficulty != "" {
+		value, err := strconv.ParseUint(difficulty, 10, 8)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("difficulty is invalid: %s", err), http.StatusBadRequest)
+			return
+		}
+		exercise.Difficulty = uint8(value)
+	}
 	req := requests.CreateExerciseRequest{
 		Exercise:   exercise,
 		TagIds:     r.FormValue("tagIds"),
 		File:       &file,
 		FileHeader: header,
 	}
-	result, err := router.useCase.Create(&req)
+	result, err := router.useCase.Create(currentUser(r), &req)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
 		return
@@ -137,6 +154,10 @@ func (router *ExercisesRouter) mux(w http.ResponseWriter, r *http.Request) {
 		router.get(idInt, w, r)
 		return
 	}
+	if !hasRole(r, editors...) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
 	if r.Method == http.MethodPost {
 		router.update(idInt, w, r)
 		return
@@ -147,8 +168,8 @@ func (router *ExercisesRouter) mux(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func (router *ExercisesRouter) get(id int, w http.ResponseWriter, _ *http.Request) {
-	result, err := router.useCase.Find(id)
+func (router *ExercisesRouter) get(id int, w http.ResponseWriter, r *http.Request) {
+	result, err := router.useCase.Find(currentUser(r), id)
 	if errors.Is(err, gorm.ErrRecordNotFound) {
 		http.Error(w, err.Error(), http.StatusNotFound)
 		return
@@ -178,7 +199,11 @@ func (router *ExercisesRouter) update(id int, w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	result, err := router.useCase.Update(id, &req)
+	result, err := router.useCase.Update(currentUser(r), id, &req)
+	if errors.Is(err, use_cases.ErrNotOwner) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
 		return
@@ -197,8 +222,12 @@ func (router *ExercisesRouter) update(id int, w http.ResponseWriter, r *http.Req
 	}
 }
 
-func (router *ExercisesRouter) delete(id int, w http.ResponseWriter, _ *http.Request) {
-	err := router.useCase.Delete(id)
+func (router *ExercisesRouter) delete(id int, w http.ResponseWriter, r *http.Request) {
+	err := router.useCase.Delete(currentUser(r), id)
+	if errors.Is(err, use_cases.ErrNotOwner) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
 		return
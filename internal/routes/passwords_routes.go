@@ -0,0 +1,110 @@
+package routes
+
+import (
+	"bf_me/internal/requests"
+	"bf_me/internal/services"
+	"bf_me/internal/storage"
+	"bf_me/internal/use_cases"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type PasswordsRouter struct {
+	useCase     *use_cases.PasswordsUseCase
+	authUseCase *use_cases.SessionsUseCase
+}
+
+func newPasswordsRouter(st *storage.Storage, mailer services.Mailer, resetURL string) *PasswordsRouter {
+	return &PasswordsRouter{
+		useCase:     use_cases.NewPasswordsUseCase(st, mailer, resetURL),
+		authUseCase: use_cases.NewSessionsUseCase(st),
+	}
+}
+
+func RegisterPasswordsRoutes(mux *http.ServeMux, st *storage.Storage, mailer services.Mailer, resetURL string) {
+	router := newPasswordsRouter(st, mailer, resetURL)
+	mux.HandleFunc("/api/v1/password/change", AuthMiddleware(router.authUseCase, router.change))
+	mux.HandleFunc("/api/v1/password/reset_request", router.requestReset)
+	mux.HandleFunc("/api/v1/password/reset", router.reset)
+}
+
+// change sets new password, other devices of user are logged out
+func (router *PasswordsRouter) change(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "No such endpoint", http.StatusNotFound)
+		return
+	}
+
+	var req requests.ChangePasswordRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	var sessionID *pgtype.UUID
+	if session := currentSession(r); session != nil {
+		sessionID = &session.ID
+	}
+	err := router.useCase.Change(currentUser(r), sessionID, &req)
+	if errors.Is(err, use_cases.ErrWrongPassword) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if _, err = w.Write([]byte("ok")); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// requestReset answers the same whether user exists or not
+func (router *PasswordsRouter) requestReset(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "No such endpoint", http.StatusNotFound)
+		return
+	}
+
+	var req requests.RequestPasswordResetRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	router.useCase.RequestReset(&req)
+
+	w.WriteHeader(http.StatusAccepted)
+	if _, err := w.Write([]byte("if the user has an email, a reset link was sent to it")); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (router *PasswordsRouter) reset(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "No such endpoint", http.StatusNotFound)
+		return
+	}
+
+	var req requests.ResetPasswordRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	err := router.useCase.Reset(&req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if _, err = w.Write([]byte("ok")); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,332 @@
+package routes
+
+import (
+	"bf_me/internal/models"
+	"bf_me/internal/presenters"
+	"bf_me/internal/requests"
+	"bf_me/internal/storage"
+	"bf_me/internal/use_cases"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"gorm.io/gorm"
+)
+
+type ProgramsRouter struct {
+	presenter   *presenters.Presenter
+	useCase     *use_cases.ProgramsUseCase
+	authUseCase *use_cases.SessionsUseCase
+}
+
+func newProgramsRouter(st *storage.Storage) *ProgramsRouter {
+	return &ProgramsRouter{
+		presenter:   presenters.NewPresenter(),
+		useCase:     use_cases.NewProgramsUseCase(st),
+		authUseCase: use_cases.NewSessionsUseCase(st),
+	}
+}
+
+func RegisterProgramsRoutes(mux *http.ServeMux, st *storage.Storage) {
+	router := newProgramsRouter(st)
+	mux.HandleFunc("/api/v1/programs/create", AuthMiddleware(router.authUseCase, router.create, editors...))
+	mux.HandleFunc("/api/v1/programs/list", AuthMiddleware(router.authUseCase, router.list))
+	mux.HandleFunc("/api/v1/programs/{id}/days", AuthMiddleware(router.authUseCase, router.setDay, editors...))
+	mux.HandleFunc("/api/v1/programs/{id}/days/{day_id}", AuthMiddleware(router.authUseCase, router.removeDay, editors...))
+	mux.HandleFunc("/api/v1/programs/{id}/toggle_draft", AuthMiddleware(router.authUseCase, router.toggleDraft, editors...))
+	mux.HandleFunc("/api/v1/programs/{id}/validate", AuthMiddleware(router.authUseCase, router.validate))
+	mux.HandleFunc("/api/v1/programs/{id}", AuthMiddleware(router.authUseCase, router.mux))
+}
+
+func (router *ProgramsRouter) create(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "No such endpoint", http.StatusNotFound)
+		return
+	}
+
+	var req requests.ProgramRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	program, trainings, err := router.useCase.Create(currentUser(r), &req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	router.writeProgram(w, program, trainings, http.StatusCreated)
+}
+
+func (router *ProgramsRouter) list(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "No such endpoint", http.StatusNotFound)
+		return
+	}
+
+	req := requests.FilterRequestBody{UpdatedAt: "DESC"}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	result, err := router.useCase.List(currentUser(r), &req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	byteData, err := json.Marshal(router.presenter.Programs(result))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err = w.Write(byteData); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (router *ProgramsRouter) setDay(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "No such endpoint", http.StatusNotFound)
+		return
+	}
+
+	idInt, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, fmt.Errorf("invalid id provided: %s", err).Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	var req requests.SetProgramDayRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	program, trainings, err := router.useCase.SetDay(currentUser(r), idInt, &req)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, use_cases.ErrNotOwner) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	router.writeProgram(w, program, trainings, http.StatusOK)
+}
+
+func (router *ProgramsRouter) removeDay(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "No such endpoint", http.StatusNotFound)
+		return
+	}
+
+	idInt, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, fmt.Errorf("invalid id provided: %s", err).Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	dayID, err := strconv.ParseUint(r.PathValue("day_id"), 10, 64)
+	if err != nil {
+		http.Error(w, fmt.Errorf("invalid day id provided: %s", err).Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	program, trainings, err := router.useCase.RemoveDay(currentUser(r), idInt, uint(dayID))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, use_cases.ErrNotOwner) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	router.writeProgram(w, program, trainings, http.StatusOK)
+}
+
+func (router *ProgramsRouter) toggleDraft(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "No such endpoint", http.StatusNotFound)
+		return
+	}
+
+	idInt, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, fmt.Errorf("invalid id provided: %s", err).Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	program, trainings, err := router.useCase.ToggleDraft(currentUser(r), idInt)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, use_cases.ErrNotOwner) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	var notReady *use_cases.NotReadyError
+	if errors.As(err, &notReady) {
+		router.writeValidation(w, notReady.Issues, http.StatusUnprocessableEntity)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	router.writeProgram(w, program, trainings, http.StatusOK)
+}
+
+func (router *ProgramsRouter) validate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "No such endpoint", http.StatusNotFound)
+		return
+	}
+
+	idInt, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, fmt.Errorf("invalid id provided: %s", err).Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	issues, err := router.useCase.Validate(currentUser(r), idInt)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	router.writeValidation(w, issues, http.StatusOK)
+}
+
+func (router *ProgramsRouter) mux(w http.ResponseWriter, r *http.Request) {
+	idInt, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, fmt.Errorf("invalid id provided: %s", err).Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		router.get(idInt, w, r)
+		return
+	}
+	if !hasRole(r, editors...) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	if r.Method == http.MethodPost {
+		router.update(idInt, w, r)
+		return
+	}
+	if r.Method == http.MethodDelete {
+		router.delete(idInt, w, r)
+		return
+	}
+}
+
+func (router *ProgramsRouter) get(id int, w http.ResponseWriter, r *http.Request) {
+	program, trainings, err := router.useCase.Find(currentUser(r), id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	router.writeProgram(w, program, trainings, http.StatusOK)
+}
+
+func (router *ProgramsRouter) update(id int, w http.ResponseWriter, r *http.Request) {
+	var req requests.ProgramRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	program, trainings, err := router.useCase.Update(currentUser(r), id, &req)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, use_cases.ErrNotOwner) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	router.writeProgram(w, program, trainings, http.StatusOK)
+}
+
+func (router *ProgramsRouter) delete(id int, w http.ResponseWriter, r *http.Request) {
+	err := router.useCase.Delete(currentUser(r), id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, use_cases.ErrNotOwner) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	if _, err = w.Write([]byte("successfully deleted")); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (router *ProgramsRouter) writeProgram(w http.ResponseWriter, program *models.Program, trainings []models.Training, status int) {
+	byteData, err := json.Marshal(router.presenter.Program(program, trainings))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("json encoding err: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if _, err = w.Write(byteData); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (router *ProgramsRouter) writeValidation(w http.ResponseWriter, issues []use_cases.Issue, status int) {
+	byteData, err := json.Marshal(router.presenter.Validation(issues))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("json encoding err: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if _, err = w.Write(byteData); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,203 @@
+package routes
+
+import (
+	"bf_me/internal/presenters"
+	"bf_me/internal/requests"
+	"bf_me/internal/storage"
+	"bf_me/internal/use_cases"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// streamTick is how often SSE stream sends run state
+const streamTick = time.Second
+
+type RunsRouter struct {
+	presenter   *presenters.Presenter
+	useCase     *use_cases.RunsUseCase
+	authUseCase *use_cases.SessionsUseCase
+}
+
+func newRunsRouter(st *storage.Storage) *RunsRouter {
+	return &RunsRouter{
+		presenter:   presenters.NewPresenter(),
+		useCase:     use_cases.NewRunsUseCase(st),
+		authUseCase: use_cases.NewSessionsUseCase(st),
+	}
+}
+
+func RegisterRunsRoutes(mux *http.ServeMux, st *storage.Storage) {
+	router := newRunsRouter(st)
+	mux.HandleFunc("/api/v1/runs/start", AuthMiddleware(router.authUseCase, router.start))
+	// action is enum of ["pause", "resume", "skip", "finish"]
+	mux.HandleFunc("/api/v1/runs/{id}/{action}", AuthMiddleware(router.authUseCase, router.handleAction))
+	mux.HandleFunc("/api/v1/runs/{id}/stream", AuthMiddleware(router.authUseCase, router.stream))
+	mux.HandleFunc("/api/v1/runs/{id}", AuthMiddleware(router.authUseCase, router.get))
+}
+
+func (router *RunsRouter) start(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "No such endpoint", http.StatusNotFound)
+		return
+	}
+
+	var req requests.StartRunRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	state, err := router.useCase.Start(currentUser(r), &req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	router.writeState(w, state, http.StatusCreated)
+}
+
+func (router *RunsRouter) get(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "No such endpoint", http.StatusNotFound)
+		return
+	}
+
+	idInt, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, fmt.Errorf("invalid id provided: %s", err).Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	state, err := router.useCase.State(currentUser(r).ID, idInt)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	router.writeState(w, state, http.StatusOK)
+}
+
+func (router *RunsRouter) handleAction(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "No such endpoint", http.StatusNotFound)
+		return
+	}
+
+	idInt, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, fmt.Errorf("invalid id provided: %s", err).Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	userID := currentUser(r).ID
+	var state use_cases.RunState
+	switch r.PathValue("action") {
+	case "pause":
+		state, err = router.useCase.Pause(userID, idInt)
+	case "resume":
+		state, err = router.useCase.Resume(userID, idInt)
+	case "skip":
+		state, err = router.useCase.Skip(userID, idInt)
+	case "finish":
+		state, err = router.useCase.Finish(userID, idInt)
+	default:
+		http.Error(w, "No such endpoint", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	router.writeState(w, state, http.StatusOK)
+}
+
+// stream sends run state as server-sent events every tick until run is finished or client is gone,
+// every screen following the run gets changes made by the others
+func (router *RunsRouter) stream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "No such endpoint", http.StatusNotFound)
+		return
+	}
+
+	idInt, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, fmt.Errorf("invalid id provided: %s", err).Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	run, segments, err := router.useCase.Find(currentUser(r).ID, idInt)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	ticker := time.NewTicker(streamTick)
+	defer ticker.Stop()
+	for {
+		state := use_cases.RunStateAt(run, segments, time.Now())
+		byteData, err := json.Marshal(router.presenter.RunState(state))
+		if err != nil {
+			return
+		}
+		if _, err = fmt.Fprintf(w, "event: state\ndata: %s\n\n", byteData); err != nil {
+			return
+		}
+		flusher.Flush()
+		if state.Status == use_cases.RunFinished {
+			return
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+		}
+		if err = router.useCase.Reload(run); err != nil {
+			return
+		}
+	}
+}
+
+func (router *RunsRouter) writeState(w http.ResponseWriter, state use_cases.RunState, status int) {
+	byteData, err := json.Marshal(router.presenter.RunState(state))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("json encoding err: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if _, err = w.Write(byteData); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
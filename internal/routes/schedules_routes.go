@@ -0,0 +1,234 @@
+package routes
+
+import (
+	"bf_me/internal/models"
+	"bf_me/internal/presenters"
+	"bf_me/internal/requests"
+	"bf_me/internal/storage"
+	"bf_me/internal/use_cases"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+type SchedulesRouter struct {
+	presenter   *presenters.Presenter
+	useCase     *use_cases.SchedulesUseCase
+	authUseCase *use_cases.SessionsUseCase
+}
+
+func newSchedulesRouter(st *storage.Storage) *SchedulesRouter {
+	return &SchedulesRouter{
+		presenter:   presenters.NewPresenter(),
+		useCase:     use_cases.NewSchedulesUseCase(st),
+		authUseCase: use_cases.NewSessionsUseCase(st),
+	}
+}
+
+func RegisterSchedulesRoutes(mux *http.ServeMux, st *storage.Storage) {
+	router := newSchedulesRouter(st)
+	mux.HandleFunc("/api/v1/schedule/create", AuthMiddleware(router.authUseCase, router.create))
+	mux.HandleFunc("/api/v1/schedule/range", AuthMiddleware(router.authUseCase, router.rangeQuery))
+	mux.HandleFunc("/api/v1/schedule/feed_token", AuthMiddleware(router.authUseCase, router.feedToken))
+	// feed is opened by calendar apps, the secret token in path is the only authorization
+	mux.HandleFunc("/api/v1/schedule/feed/{file}", router.feed)
+	mux.HandleFunc("/api/v1/schedule/{id}", AuthMiddleware(router.authUseCase, router.mux))
+}
+
+func (router *SchedulesRouter) create(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "No such endpoint", http.StatusNotFound)
+		return
+	}
+
+	var req requests.ScheduleRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	result, err := router.useCase.Create(currentUser(r), &req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	router.writeScheduledSession(w, result, http.StatusCreated)
+}
+
+func (router *SchedulesRouter) rangeQuery(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "No such endpoint", http.StatusNotFound)
+		return
+	}
+
+	var req requests.ScheduleRangeRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	result, err := router.useCase.Range(currentUser(r).ID, &req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	byteData, err := json.Marshal(router.presenter.Occurrences(result))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("json encoding err: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err = w.Write(byteData); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (router *SchedulesRouter) feedToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "No such endpoint", http.StatusNotFound)
+		return
+	}
+
+	token, err := router.useCase.IssueFeedToken(currentUser(r).ID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	byteData, err := json.Marshal(router.presenter.FeedToken(token))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("json encoding err: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+
+	if _, err = w.Write(byteData); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (router *SchedulesRouter) feed(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "No such endpoint", http.StatusNotFound)
+		return
+	}
+
+	token, ok := strings.CutSuffix(r.PathValue("file"), ".ics")
+	if !ok || token == "" {
+		http.Error(w, "No such endpoint", http.StatusNotFound)
+		return
+	}
+
+	sessions, durations, err := router.useCase.Feed(token)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, "No such feed", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err = w.Write([]byte(router.presenter.ICalendar(sessions, durations))); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (router *SchedulesRouter) mux(w http.ResponseWriter, r *http.Request) {
+	idInt, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, fmt.Errorf("invalid id provided: %s", err).Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		router.get(idInt, w, r)
+		return
+	}
+	if r.Method == http.MethodPost {
+		router.update(idInt, w, r)
+		return
+	}
+	if r.Method == http.MethodDelete {
+		router.delete(idInt, w, r)
+		return
+	}
+}
+
+func (router *SchedulesRouter) get(id int, w http.ResponseWriter, r *http.Request) {
+	result, err := router.useCase.Find(currentUser(r).ID, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	router.writeScheduledSession(w, result, http.StatusOK)
+}
+
+func (router *SchedulesRouter) update(id int, w http.ResponseWriter, r *http.Request) {
+	var req requests.ScheduleRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	result, err := router.useCase.Update(currentUser(r), id, &req)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	router.writeScheduledSession(w, result, http.StatusOK)
+}
+
+func (router *SchedulesRouter) delete(id int, w http.ResponseWriter, r *http.Request) {
+	err := router.useCase.Delete(currentUser(r).ID, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	if _, err = w.Write([]byte("successfully deleted")); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (router *SchedulesRouter) writeScheduledSession(w http.ResponseWriter, s *models.ScheduledSession, status int) {
+	byteData, err := json.Marshal(router.presenter.ScheduledSession(s))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("json encoding err: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if _, err = w.Write(byteData); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
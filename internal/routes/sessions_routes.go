@@ -1,14 +1,18 @@
 package routes
 
 import (
+	"bf_me/internal/models"
 	"bf_me/internal/presenters"
 	"bf_me/internal/requests"
 	"bf_me/internal/storage"
 	"bf_me/internal/use_cases"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
-	"strings"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"gorm.io/gorm"
 )
 
 type SessionRouter struct {
@@ -25,88 +29,123 @@ func NewSessionsRouter(st *storage.Storage) *SessionRouter {
 
 func RegisterSessionsRoutes(mux *http.ServeMux, st *storage.Storage) {
 	router := NewSessionsRouter(st)
-	mux.HandleFunc("/api/v1/vragneproidet", router.register)
 	mux.HandleFunc("/api/v1/login", router.login)
 	mux.HandleFunc("/api/v1/logout", AuthMiddleware(router.useCase, router.logout))
+	mux.HandleFunc("/api/v1/sessions/list", AuthMiddleware(router.useCase, router.list))
+	mux.HandleFunc("/api/v1/sessions/{id}", AuthMiddleware(router.useCase, router.revoke))
 }
 
-func (router *SessionRouter) register(w http.ResponseWriter, r *http.Request) {
+func (router *SessionRouter) login(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "No such endpoint", http.StatusNotFound)
 		return
 	}
 
-	var req requests.UserRequestBody
+	var req *requests.UserRequestBody
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
 		return
 	}
 
-	session, err := router.useCase.CreateUser(req)
+	session, err := router.useCase.Create(req, r.UserAgent(), clientIP(r))
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
 		return
 	}
 
-	byteData, err := json.Marshal(router.presenter.Session(session))
+	router.writeSession(w, session, req.Cookie)
+}
+
+func (router *SessionRouter) logout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "No such endpoint", http.StatusNotFound)
+		return
+	}
+
+	session := currentSession(r)
+	err := router.useCase.Revoke(session.UserID, session.ID)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("json encoding err: %s", err.Error()), http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
 		return
 	}
+	clearSessionCookies(w)
 
 	w.WriteHeader(http.StatusOK)
-	if _, err = w.Write(byteData); err != nil {
+	if _, err = w.Write([]byte("ok")); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 	}
 }
 
-func (router *SessionRouter) login(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
+// list shows devices where the current user is logged in
+func (router *SessionRouter) list(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
 		http.Error(w, "No such endpoint", http.StatusNotFound)
 		return
 	}
 
-	var req *requests.UserRequestBody
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
-		return
-	}
-
-	session, err := router.useCase.Create(req)
+	session := currentSession(r)
+	result, err := router.useCase.List(session.UserID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
 		return
 	}
 
-	byteData, err := json.Marshal(router.presenter.Session(session))
+	byteData, err := json.Marshal(router.presenter.Devices(result, session))
 	if err != nil {
 		http.Error(w, fmt.Sprintf("json encoding err: %s", err.Error()), http.StatusInternalServerError)
 		return
 	}
-
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
+
 	if _, err = w.Write(byteData); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 	}
 }
 
-func (router *SessionRouter) logout(w http.ResponseWriter, r *http.Request) {
+// revoke logs the current user out of one device
+func (router *SessionRouter) revoke(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodDelete {
 		http.Error(w, "No such endpoint", http.StatusNotFound)
 		return
 	}
 
-	bearerToken := r.Header.Get("Authorization")
-	sessionId := strings.TrimPrefix(bearerToken, "Bearer token=")
+	var id pgtype.UUID
+	if err := id.Scan(r.PathValue("id")); err != nil {
+		http.Error(w, fmt.Errorf("invalid id provided: %s", err).Error(), http.StatusUnprocessableEntity)
+		return
+	}
 
-	err := router.useCase.Delete(sessionId)
+	err := router.useCase.Revoke(currentSession(r).UserID, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
 		return
 	}
 
 	w.WriteHeader(http.StatusOK)
-	if _, err = w.Write([]byte("ok")); err != nil {
+	if _, err = w.Write([]byte("successfully deleted")); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// writeSession gives token to the client, in cookie mode the token is set as cookie and only CSRF token is returned
+func (router *SessionRouter) writeSession(w http.ResponseWriter, session *models.Session, cookie bool) {
+	byteData, err := json.Marshal(router.presenter.Session(session, cookie))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("json encoding err: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	if cookie {
+		setSessionCookies(w, session)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if _, err = w.Write(byteData); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 	}
 }
@@ -0,0 +1,94 @@
+package routes
+
+import (
+	"bf_me/internal/presenters"
+	"bf_me/internal/requests"
+	"bf_me/internal/storage"
+	"bf_me/internal/use_cases"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"gorm.io/gorm"
+)
+
+type SharingRouter struct {
+	presenter   *presenters.Presenter
+	useCase     *use_cases.SharingUseCase
+	authUseCase *use_cases.SessionsUseCase
+}
+
+func newSharingRouter(st *storage.Storage) *SharingRouter {
+	return &SharingRouter{
+		presenter:   presenters.NewPresenter(),
+		useCase:     use_cases.NewSharingUseCase(st),
+		authUseCase: use_cases.NewSessionsUseCase(st),
+	}
+}
+
+func RegisterSharingRoutes(mux *http.ServeMux, st *storage.Storage) {
+	router := newSharingRouter(st)
+	mux.HandleFunc("/api/v1/exercises/{id}/sharing", AuthMiddleware(router.authUseCase, router.handle(use_cases.KindExercise)))
+	mux.HandleFunc("/api/v1/blocks/{id}/sharing", AuthMiddleware(router.authUseCase, router.handle(use_cases.KindBlock)))
+	mux.HandleFunc("/api/v1/trainings/{id}/sharing", AuthMiddleware(router.authUseCase, router.handle(use_cases.KindTraining)))
+	mux.HandleFunc("/api/v1/programs/{id}/sharing", AuthMiddleware(router.authUseCase, router.handle(use_cases.KindProgram)))
+}
+
+// handle returns sharing of content of kind on GET and changes it on POST
+func (router *SharingRouter) handle(kind string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodPost {
+			http.Error(w, "No such endpoint", http.StatusNotFound)
+			return
+		}
+
+		idInt, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil {
+			http.Error(w, fmt.Errorf("invalid id provided: %s", err).Error(), http.StatusUnprocessableEntity)
+			return
+		}
+
+		var result use_cases.Sharing
+		if r.Method == http.MethodGet {
+			result, err = router.useCase.Find(currentUser(r), kind, idInt)
+		} else {
+			if !hasRole(r, editors...) {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+			var req requests.SharingRequestBody
+			if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+				return
+			}
+			result, err = router.useCase.Share(currentUser(r), kind, idInt, &req)
+		}
+
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, use_cases.ErrNotOwner) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+
+		byteData, err := json.Marshal(router.presenter.Sharing(result))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("json encoding err: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+
+		if _, err = w.Write(byteData); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	}
+}
@@ -32,12 +32,21 @@ func newTrainingsRouter(st *storage.Storage) *TrainingRouter {
 
 func RegisterTrainingsRoutes(mux *http.ServeMux, st *storage.Storage) {
 	router := newTrainingsRouter(st)
-	mux.HandleFunc("/api/v1/trainings/create", AuthMiddleware(router.authUseCase, router.create))
+	mux.HandleFunc("/api/v1/trainings/create", AuthMiddleware(router.authUseCase, router.create, editors...))
 	mux.HandleFunc("/api/v1/trainings/list", AuthMiddleware(router.authUseCase, router.list))
-
-	// action is enum of ["add", "remove"]
-	mux.HandleFunc("/api/v1/trainings/{training_id}/{action}/block/{block_id}", AuthMiddleware(router.authUseCase, router.handleBlock))
-	mux.HandleFunc("/api/v1/trainings/{id}/toggle_draft", AuthMiddleware(router.authUseCase, router.toggleDraft))
+	mux.HandleFunc("/api/v1/trainings/generate", AuthMiddleware(router.authUseCase, router.generate, editors...))
+
+	// action is enum of ["add", "insert", "remove"]
+	mux.HandleFunc("/api/v1/trainings/{training_id}/{action}/block/{block_id}", AuthMiddleware(router.authUseCase, router.handleBlock, editors...))
+	mux.HandleFunc("/api/v1/trainings/{training_id}/slots/{slot_id}", AuthMiddleware(router.authUseCase, router.handleSlot, editors...))
+	mux.HandleFunc("/api/v1/trainings/{id}/move", AuthMiddleware(router.authUseCase, router.moveBlock, editors...))
+	mux.HandleFunc("/api/v1/trainings/{id}/reorder", AuthMiddleware(router.authUseCase, router.reorderBlocks, editors...))
+	mux.HandleFunc("/api/v1/trainings/{id}/toggle_draft", AuthMiddleware(router.authUseCase, router.toggleDraft, editors...))
+	mux.HandleFunc("/api/v1/trainings/{id}/validate", AuthMiddleware(router.authUseCase, router.validate))
+	mux.HandleFunc("/api/v1/trainings/{id}/timeline", AuthMiddleware(router.authUseCase, router.timeline))
+	mux.HandleFunc("/api/v1/trainings/{id}/clone", AuthMiddleware(router.authUseCase, router.clone, editors...))
+	mux.HandleFunc("/api/v1/trainings/{id}/upgrades", AuthMiddleware(router.authUseCase, router.upgrades))
+	mux.HandleFunc("/api/v1/trainings/{id}/upgrade", AuthMiddleware(router.authUseCase, router.upgrade, editors...))
 	mux.HandleFunc("/api/v1/trainings/{id}", AuthMiddleware(router.authUseCase, router.mux))
 }
 
@@ -53,7 +62,7 @@ func (router *TrainingRouter) create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := router.useCase.Create(&req)
+	result, err := router.useCase.Create(currentUser(r), &req)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
 		return
@@ -72,6 +81,37 @@ func (router *TrainingRouter) create(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (router *TrainingRouter) generate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "No such endpoint", http.StatusNotFound)
+		return
+	}
+
+	var req requests.GenerateTrainingRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	training, blocks, err := router.useCase.Generate(currentUser(r), &req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	byteData, err := json.Marshal(router.presenter.Training(training, blocks))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("json encoding err: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+
+	if _, err = w.Write(byteData); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
 func (router *TrainingRouter) list(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "No such endpoint", http.StatusNotFound)
@@ -84,7 +124,7 @@ func (router *TrainingRouter) list(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := router.useCase.List(&req)
+	result, err := router.useCase.List(currentUser(r), &req)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
 		return
@@ -112,7 +152,7 @@ func (router *TrainingRouter) handleBlock(w http.ResponseWriter, r *http.Request
 	}
 
 	action := r.PathValue("action")
-	if slices.Contains([]string{"add", "remove"}, action) == false {
+	if slices.Contains([]string{"add", "insert", "remove"}, action) == false {
 		http.Error(w, "No such endpoint", http.StatusNotFound)
 		return
 	}
@@ -133,15 +173,26 @@ func (router *TrainingRouter) handleBlock(w http.ResponseWriter, r *http.Request
 	var training *models.Training
 	var blocks []models.Block
 	if action == "add" {
-		training, blocks, err = router.useCase.AddTrainingBlock(uint(trainingID), uint(blockID))
+		training, blocks, err = router.useCase.AddTrainingBlock(currentUser(r), uint(trainingID), uint(blockID))
+	} else if action == "insert" {
+		req := requests.InsertTrainingBlockRequestBody{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		training, blocks, err = router.useCase.InsertTrainingBlock(currentUser(r), uint(trainingID), uint(blockID), &req)
 	} else {
-		training, blocks, err = router.useCase.RemoveTrainingBlock(uint(trainingID), uint(blockID))
+		training, blocks, err = router.useCase.RemoveTrainingBlock(currentUser(r), uint(trainingID), uint(blockID))
 	}
 
 	if errors.Is(err, gorm.ErrRecordNotFound) {
 		http.Error(w, err.Error(), http.StatusNotFound)
 		return
 	}
+	if errors.Is(err, use_cases.ErrNotOwner) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
 		return
@@ -162,6 +213,152 @@ func (router *TrainingRouter) handleBlock(w http.ResponseWriter, r *http.Request
 	}
 }
 
+func (router *TrainingRouter) handleSlot(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodDelete {
+		http.Error(w, "No such endpoint", http.StatusNotFound)
+		return
+	}
+
+	trainingID, err := strconv.Atoi(r.PathValue("training_id"))
+	if err != nil {
+		http.Error(w, fmt.Errorf("invalid id provided: %s", err).Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	slotID, err := strconv.Atoi(r.PathValue("slot_id"))
+	if err != nil {
+		http.Error(w, fmt.Errorf("invalid id provided: %s", err).Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	var training *models.Training
+	var blocks []models.Block
+	if r.Method == http.MethodPost {
+		var req requests.UpdateTrainingSlotRequestBody
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		training, blocks, err = router.useCase.UpdateTrainingSlot(currentUser(r), uint(trainingID), uint(slotID), &req)
+	} else {
+		training, blocks, err = router.useCase.RemoveTrainingSlot(currentUser(r), uint(trainingID), uint(slotID))
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, use_cases.ErrNotOwner) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	byteData, err := json.Marshal(router.presenter.Training(training, blocks))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("json encoding err: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err = w.Write(byteData); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (router *TrainingRouter) moveBlock(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "No such endpoint", http.StatusNotFound)
+		return
+	}
+
+	idInt, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, fmt.Errorf("invalid id provided: %s", err).Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	var req requests.MoveSlotRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	training, blocks, err := router.useCase.MoveTrainingBlock(currentUser(r), uint(idInt), &req)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, use_cases.ErrNotOwner) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	byteData, err := json.Marshal(router.presenter.Training(training, blocks))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("json encoding err: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err = w.Write(byteData); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (router *TrainingRouter) reorderBlocks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "No such endpoint", http.StatusNotFound)
+		return
+	}
+
+	idInt, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, fmt.Errorf("invalid id provided: %s", err).Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	var req requests.ReorderSlotsRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	training, blocks, err := router.useCase.ReorderTrainingBlocks(currentUser(r), uint(idInt), &req)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, use_cases.ErrNotOwner) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	byteData, err := json.Marshal(router.presenter.Training(training, blocks))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("json encoding err: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err = w.Write(byteData); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
 func (router *TrainingRouter) mux(w http.ResponseWriter, r *http.Request) {
 	idInt, err := strconv.Atoi(r.PathValue("id"))
 	if err != nil {
@@ -173,6 +370,10 @@ func (router *TrainingRouter) mux(w http.ResponseWriter, r *http.Request) {
 		router.get(idInt, w, r)
 		return
 	}
+	if !hasRole(r, editors...) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
 	if r.Method == http.MethodPost {
 		router.update(idInt, w, r)
 		return
@@ -195,7 +396,58 @@ func (router *TrainingRouter) toggleDraft(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	training, blocks, err := router.useCase.ToggleDraft(idInt)
+	training, blocks, err := router.useCase.ToggleDraft(currentUser(r), idInt)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	var notReady *use_cases.NotReadyError
+	if errors.As(err, &notReady) {
+		router.writeValidation(w, notReady.Issues, http.StatusUnprocessableEntity)
+		return
+	}
+	if errors.Is(err, use_cases.ErrNotOwner) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	byteData, err := json.Marshal(router.presenter.Training(training, blocks))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("json encoding err: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err = w.Write(byteData); err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	}
+
+}
+
+func (router *TrainingRouter) clone(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "No such endpoint", http.StatusNotFound)
+		return
+	}
+
+	idInt, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, fmt.Errorf("invalid id provided: %s", err).Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	var req requests.CloneTrainingRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	training, blocks, err := router.useCase.Clone(currentUser(r), idInt, &req)
 	if errors.Is(err, gorm.ErrRecordNotFound) {
 		http.Error(w, err.Error(), http.StatusNotFound)
 		return
@@ -211,16 +463,163 @@ func (router *TrainingRouter) toggleDraft(w http.ResponseWriter, r *http.Request
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+
+	if _, err = w.Write(byteData); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (router *TrainingRouter) upgrades(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "No such endpoint", http.StatusNotFound)
+		return
+	}
+
+	idInt, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, fmt.Errorf("invalid id provided: %s", err).Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	upgrades, err := router.useCase.Upgrades(currentUser(r), idInt)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	byteData, err := json.Marshal(router.presenter.BlockUpgrades(upgrades))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("json encoding err: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 
 	if _, err = w.Write(byteData); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (router *TrainingRouter) upgrade(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "No such endpoint", http.StatusNotFound)
+		return
+	}
+
+	idInt, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, fmt.Errorf("invalid id provided: %s", err).Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	training, blocks, upgrades, err := router.useCase.Upgrade(currentUser(r), idInt)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, use_cases.ErrNotOwner) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	byteData, err := json.Marshal(router.presenter.TrainingUpgrade(training, blocks, upgrades))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("json encoding err: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err = w.Write(byteData); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (router *TrainingRouter) timeline(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "No such endpoint", http.StatusNotFound)
+		return
+	}
+
+	idInt, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, fmt.Errorf("invalid id provided: %s", err).Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	training, segments, err := router.useCase.Timeline(currentUser(r), idInt)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	byteData, err := json.Marshal(router.presenter.Timeline(training.ID, segments))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("json encoding err: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err = w.Write(byteData); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (router *TrainingRouter) validate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "No such endpoint", http.StatusNotFound)
+		return
+	}
+
+	idInt, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, fmt.Errorf("invalid id provided: %s", err).Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	issues, err := router.useCase.Validate(currentUser(r), idInt)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
 		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	router.writeValidation(w, issues, http.StatusOK)
+}
+
+func (router *TrainingRouter) writeValidation(w http.ResponseWriter, issues []use_cases.Issue, status int) {
+	byteData, err := json.Marshal(router.presenter.Validation(issues))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("json encoding err: %s", err.Error()), http.StatusInternalServerError)
+		return
 	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
 
+	if _, err = w.Write(byteData); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
 }
 
-func (router *TrainingRouter) get(id int, w http.ResponseWriter, _ *http.Request) {
-	training, blocks, err := router.useCase.Find(id)
+func (router *TrainingRouter) get(id int, w http.ResponseWriter, r *http.Request) {
+	training, blocks, err := router.useCase.Find(currentUser(r), id)
 	if errors.Is(err, gorm.ErrRecordNotFound) {
 		http.Error(w, err.Error(), http.StatusNotFound)
 		return
@@ -250,7 +649,11 @@ func (router *TrainingRouter) update(id int, w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	training, blocks, err := router.useCase.Update(id, &req)
+	training, blocks, err := router.useCase.Update(currentUser(r), id, &req)
+	if errors.Is(err, use_cases.ErrNotOwner) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
 		return
@@ -269,8 +672,12 @@ func (router *TrainingRouter) update(id int, w http.ResponseWriter, r *http.Requ
 	}
 }
 
-func (router *TrainingRouter) delete(id int, w http.ResponseWriter, _ *http.Request) {
-	err := router.useCase.Delete(id)
+func (router *TrainingRouter) delete(id int, w http.ResponseWriter, r *http.Request) {
+	err := router.useCase.Delete(currentUser(r), id)
+	if errors.Is(err, use_cases.ErrNotOwner) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
 		return
@@ -0,0 +1,200 @@
+package routes
+
+import (
+	"bf_me/internal/models"
+	"bf_me/internal/presenters"
+	"bf_me/internal/requests"
+	"bf_me/internal/storage"
+	"bf_me/internal/use_cases"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"gorm.io/gorm"
+)
+
+type UsersRouter struct {
+	presenter   *presenters.Presenter
+	useCase     *use_cases.UsersUseCase
+	authUseCase *use_cases.SessionsUseCase
+}
+
+func newUsersRouter(st *storage.Storage) *UsersRouter {
+	return &UsersRouter{
+		presenter:   presenters.NewPresenter(),
+		useCase:     use_cases.NewUsersUseCase(st),
+		authUseCase: use_cases.NewSessionsUseCase(st),
+	}
+}
+
+func RegisterUsersRoutes(mux *http.ServeMux, st *storage.Storage) {
+	router := newUsersRouter(st)
+	mux.HandleFunc("/api/v1/users/me", AuthMiddleware(router.authUseCase, router.me))
+	mux.HandleFunc("/api/v1/users/create", AuthMiddleware(router.authUseCase, router.create, use_cases.RoleAdmin))
+	mux.HandleFunc("/api/v1/users/list", AuthMiddleware(router.authUseCase, router.list, use_cases.RoleAdmin))
+	mux.HandleFunc("/api/v1/users/{id}/role", AuthMiddleware(router.authUseCase, router.setRole, use_cases.RoleAdmin))
+	// action is enum of ["disable", "enable"]
+	mux.HandleFunc("/api/v1/users/{id}/{action}", AuthMiddleware(router.authUseCase, router.handleAction, use_cases.RoleAdmin))
+	mux.HandleFunc("/api/v1/users/{id}", AuthMiddleware(router.authUseCase, router.get, use_cases.RoleAdmin))
+}
+
+func (router *UsersRouter) me(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "No such endpoint", http.StatusNotFound)
+		return
+	}
+
+	router.writeUser(w, currentUser(r), http.StatusOK)
+}
+
+func (router *UsersRouter) create(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "No such endpoint", http.StatusNotFound)
+		return
+	}
+
+	var req requests.CreateUserRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	result, err := router.useCase.Create(&req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	router.writeUser(w, result, http.StatusCreated)
+}
+
+func (router *UsersRouter) list(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "No such endpoint", http.StatusNotFound)
+		return
+	}
+
+	result, err := router.useCase.List()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	byteData, err := json.Marshal(router.presenter.Users(result))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("json encoding err: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err = w.Write(byteData); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (router *UsersRouter) get(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "No such endpoint", http.StatusNotFound)
+		return
+	}
+
+	idInt, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, fmt.Errorf("invalid id provided: %s", err).Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	result, err := router.useCase.Find(idInt)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	router.writeUser(w, result, http.StatusOK)
+}
+
+func (router *UsersRouter) setRole(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "No such endpoint", http.StatusNotFound)
+		return
+	}
+
+	idInt, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, fmt.Errorf("invalid id provided: %s", err).Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	var req requests.UserRoleRequestBody
+	if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	result, err := router.useCase.SetRole(idInt, req.Role)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	router.writeUser(w, result, http.StatusOK)
+}
+
+func (router *UsersRouter) handleAction(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "No such endpoint", http.StatusNotFound)
+		return
+	}
+
+	idInt, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, fmt.Errorf("invalid id provided: %s", err).Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	var result *models.User
+	switch r.PathValue("action") {
+	case "disable":
+		result, err = router.useCase.SetDisabled(idInt, true)
+	case "enable":
+		result, err = router.useCase.SetDisabled(idInt, false)
+	default:
+		http.Error(w, "No such endpoint", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	router.writeUser(w, result, http.StatusOK)
+}
+
+func (router *UsersRouter) writeUser(w http.ResponseWriter, user *models.User, status int) {
+	byteData, err := json.Marshal(router.presenter.User(user))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("json encoding err: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if _, err = w.Write(byteData); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,178 @@
+package routes
+
+import (
+	"bf_me/internal/models"
+	"bf_me/internal/presenters"
+	"bf_me/internal/requests"
+	"bf_me/internal/storage"
+	"bf_me/internal/use_cases"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"gorm.io/gorm"
+)
+
+type WorkoutLogsRouter struct {
+	presenter   *presenters.Presenter
+	useCase     *use_cases.WorkoutLogsUseCase
+	authUseCase *use_cases.SessionsUseCase
+}
+
+func newWorkoutLogsRouter(st *storage.Storage) *WorkoutLogsRouter {
+	return &WorkoutLogsRouter{
+		presenter:   presenters.NewPresenter(),
+		useCase:     use_cases.NewWorkoutLogsUseCase(st),
+		authUseCase: use_cases.NewSessionsUseCase(st),
+	}
+}
+
+func RegisterWorkoutLogsRoutes(mux *http.ServeMux, st *storage.Storage) {
+	router := newWorkoutLogsRouter(st)
+	mux.HandleFunc("/api/v1/logs/create", AuthMiddleware(router.authUseCase, router.create))
+	mux.HandleFunc("/api/v1/logs/list", AuthMiddleware(router.authUseCase, router.list))
+	mux.HandleFunc("/api/v1/logs/{id}", AuthMiddleware(router.authUseCase, router.mux))
+}
+
+func (router *WorkoutLogsRouter) create(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "No such endpoint", http.StatusNotFound)
+		return
+	}
+
+	var req requests.WorkoutLogRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	result, err := router.useCase.Create(currentUser(r), &req)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	router.writeWorkoutLog(w, result, http.StatusCreated)
+}
+
+func (router *WorkoutLogsRouter) list(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "No such endpoint", http.StatusNotFound)
+		return
+	}
+
+	var req requests.FilterWorkoutLogsRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	result, err := router.useCase.List(currentUser(r).ID, &req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	byteData, err := json.Marshal(router.presenter.WorkoutLogs(result))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("json encoding err: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err = w.Write(byteData); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (router *WorkoutLogsRouter) mux(w http.ResponseWriter, r *http.Request) {
+	idInt, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, fmt.Errorf("invalid id provided: %s", err).Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		router.get(idInt, w, r)
+		return
+	}
+	if r.Method == http.MethodPost {
+		router.update(idInt, w, r)
+		return
+	}
+	if r.Method == http.MethodDelete {
+		router.delete(idInt, w, r)
+		return
+	}
+}
+
+func (router *WorkoutLogsRouter) get(id int, w http.ResponseWriter, r *http.Request) {
+	result, err := router.useCase.Find(currentUser(r).ID, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	router.writeWorkoutLog(w, result, http.StatusOK)
+}
+
+func (router *WorkoutLogsRouter) update(id int, w http.ResponseWriter, r *http.Request) {
+	var req requests.WorkoutLogRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	result, err := router.useCase.Update(currentUser(r).ID, id, &req)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	router.writeWorkoutLog(w, result, http.StatusOK)
+}
+
+func (router *WorkoutLogsRouter) delete(id int, w http.ResponseWriter, r *http.Request) {
+	err := router.useCase.Delete(currentUser(r).ID, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	if _, err = w.Write([]byte("successfully deleted")); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (router *WorkoutLogsRouter) writeWorkoutLog(w http.ResponseWriter, log *models.WorkoutLog, status int) {
+	byteData, err := json.Marshal(router.presenter.WorkoutLog(log))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("json encoding err: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if _, err = w.Write(byteData); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
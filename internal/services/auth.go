@@ -1,9 +1,65 @@
 package services
 
 import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
 	"golang.org/x/crypto/bcrypt"
 )
 
+// PasswordPolicy is the strength new passwords should have, passwords set before it are not checked
+type PasswordPolicy struct {
+	MinLength        int
+	RequireMixedCase bool
+	RequireDigit     bool
+	RequireSymbol    bool
+}
+
+var passwordPolicy = PasswordPolicy{MinLength: 8, RequireDigit: true}
+
+var ErrPasswordContainsLogin = errors.New("password should not contain login")
+
+// SetPasswordPolicy replaces the default policy of 8 characters with a digit
+func SetPasswordPolicy(policy PasswordPolicy) {
+	passwordPolicy = policy
+}
+
+// CheckPassword tells what new password of user with login lacks by the policy
+func CheckPassword(password, login string) error {
+	if len([]rune(password)) < passwordPolicy.MinLength {
+		return fmt.Errorf("password should have at least %d characters", passwordPolicy.MinLength)
+	}
+	if login != "" && strings.Contains(strings.ToLower(password), strings.ToLower(login)) {
+		return ErrPasswordContainsLogin
+	}
+
+	var lower, upper, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+	if passwordPolicy.RequireMixedCase && !(lower && upper) {
+		return errors.New("password should have both lower and upper case letters")
+	}
+	if passwordPolicy.RequireDigit && !digit {
+		return errors.New("password should have a digit")
+	}
+	if passwordPolicy.RequireSymbol && !symbol {
+		return errors.New("password should have a symbol which is not a letter or a digit")
+	}
+	return nil
+}
+
 func HashPassword(password string) (string, error) {
 	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	return string(bytes), err
@@ -0,0 +1,56 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/smtp"
+	"strings"
+)
+
+// Mailer delivers emails to users, it is chosen by configuration
+type Mailer interface {
+	Send(to, subject, body string) error
+}
+
+// NewMailer returns SMTP mailer when address is given, otherwise mails are only logged for local runs
+func NewMailer(addr, username, password, from string) Mailer {
+	if addr == "" {
+		return LogMailer{}
+	}
+	return &SMTPMailer{Addr: addr, Username: username, Password: password, From: from}
+}
+
+// LogMailer writes mails to log instead of sending them
+type LogMailer struct{}
+
+func (LogMailer) Send(to, subject, body string) error {
+	log.Printf("mail to %s: %s\n%s", to, subject, body)
+	return nil
+}
+
+// SMTPMailer sends plain text mails through SMTP server, it authenticates only when Username is given
+type SMTPMailer struct {
+	Addr     string // host:port
+	Username string
+	Password string
+	From     string
+}
+
+func (m *SMTPMailer) Send(to, subject, body string) error {
+	if strings.ContainsAny(to+subject, "\r\n") {
+		return fmt.Errorf("mail header should be one line")
+	}
+
+	var auth smtp.Auth
+	if m.Username != "" {
+		host, _, err := net.SplitHostPort(m.Addr)
+		if err != nil {
+			return err
+		}
+		auth = smtp.PlainAuth("", m.Username, m.Password, host)
+	}
+	message := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n%s",
+		m.From, to, subject, body)
+	return smtp.SendMail(m.Addr, auth, m.From, []string{to}, []byte(message))
+}
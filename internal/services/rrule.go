@@ -0,0 +1,197 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	FreqDaily   = "DAILY"
+	FreqWeekly  = "WEEKLY"
+	FreqMonthly = "MONTHLY"
+
+	// maxOccurrences stops expanding of endless rules started long ago
+	maxOccurrences = 100000
+)
+
+var ErrInvalidRRule = errors.New("recurrence rule is invalid\nuse FREQ=DAILY|WEEKLY|MONTHLY with optional INTERVAL, COUNT, UNTIL and BYDAY")
+
+var weekdays = map[string]time.Weekday{
+	"MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday, "TH": time.Thursday,
+	"FR": time.Friday, "SA": time.Saturday, "SU": time.Sunday,
+}
+
+// RRule is a subset of RFC 5545 recurrence rule which is enough for training plans
+type RRule struct {
+	Freq     string
+	Interval int
+	Count    int            // zero is unlimited
+	Until    time.Time      // zero is unlimited
+	ByDay    []time.Weekday // only for weekly rules
+}
+
+// ParseRRule parses rule like `FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH;COUNT=10`, empty rule gives nil
+func ParseRRule(rule string) (*RRule, error) {
+	rule = strings.TrimPrefix(strings.TrimSpace(rule), "RRULE:")
+	if rule == "" {
+		return nil, nil
+	}
+
+	r := &RRule{Interval: 1}
+	for _, part := range strings.Split(rule, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, ErrInvalidRRule
+		}
+
+		var err error
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			r.Freq = strings.ToUpper(value)
+		case "INTERVAL":
+			r.Interval, err = strconv.Atoi(value)
+		case "COUNT":
+			r.Count, err = strconv.Atoi(value)
+		case "UNTIL":
+			r.Until, err = parseUntil(value)
+		case "BYDAY":
+			for _, day := range strings.Split(strings.ToUpper(value), ",") {
+				weekday, ok := weekdays[day]
+				if !ok {
+					return nil, fmt.Errorf("%w: unknown day %q", ErrInvalidRRule, day)
+				}
+				r.ByDay = append(r.ByDay, weekday)
+			}
+		case "WKST":
+			// weeks always start on monday
+		default:
+			return nil, fmt.Errorf("%w: %s is not supported", ErrInvalidRRule, key)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidRRule, err)
+		}
+	}
+
+	if !slices.Contains([]string{FreqDaily, FreqWeekly, FreqMonthly}, r.Freq) || r.Interval < 1 || r.Count < 0 {
+		return nil, ErrInvalidRRule
+	}
+	if len(r.ByDay) != 0 && r.Freq != FreqWeekly {
+		return nil, fmt.Errorf("%w: BYDAY is supported only for weekly rules", ErrInvalidRRule)
+	}
+	return r, nil
+}
+
+func parseUntil(value string) (time.Time, error) {
+	if t, err := time.Parse("20060102T150405Z", value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("20060102", value)
+	if err != nil {
+		return t, err
+	}
+	// date only UNTIL includes the whole day
+	return t.Add(24*time.Hour - time.Second), nil
+}
+
+// String is the canonical form of rule, it is stored and put into iCalendar feed
+func (r *RRule) String() string {
+	parts := []string{"FREQ=" + r.Freq}
+	if r.Interval > 1 {
+		parts = append(parts, fmt.Sprintf("INTERVAL=%d", r.Interval))
+	}
+	if len(r.ByDay) != 0 {
+		days := make([]string, 0, len(r.ByDay))
+		for _, weekday := range r.sortedByDay() {
+			for name, d := range weekdays {
+				if d == weekday {
+					days = append(days, name)
+				}
+			}
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if r.Count != 0 {
+		parts = append(parts, fmt.Sprintf("COUNT=%d", r.Count))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+	return strings.Join(parts, ";")
+}
+
+// StartsOn tells whether start is an occurrence of rule itself. Calendar apps always show start
+// as the first occurrence, so start out of BYDAY would be shown there and not by Between
+func (r *RRule) StartsOn(start time.Time) bool {
+	return len(r.ByDay) == 0 || slices.Contains(r.ByDay, start.Weekday())
+}
+
+// Between returns occurrences of rule started at start which are in [from, to).
+// Occurrences keep local time of start in its location, so they do not move on daylight saving changes
+func (r *RRule) Between(start, from, to time.Time) []time.Time {
+	occurrences := make([]time.Time, 0)
+	count := 0
+	for i := 0; i < maxOccurrences; i++ {
+		for _, t := range r.period(start, i) {
+			if t.Before(start) {
+				continue
+			}
+			if !t.Before(to) || (!r.Until.IsZero() && t.After(r.Until)) {
+				return occurrences
+			}
+
+			count++
+			if !t.Before(from) {
+				occurrences = append(occurrences, t)
+			}
+			if r.Count != 0 && count >= r.Count {
+				return occurrences
+			}
+		}
+	}
+	return occurrences
+}
+
+// period returns candidates of i-th period of rule in chronological order
+func (r *RRule) period(start time.Time, i int) []time.Time {
+	year, month, day := start.Date()
+	hour, minute, second := start.Clock()
+	at := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, hour, minute, second, 0, start.Location())
+	}
+
+	switch r.Freq {
+	case FreqDaily:
+		return []time.Time{at(year, month, day+i*r.Interval)}
+	case FreqMonthly:
+		t := at(year, month+time.Month(i*r.Interval), day)
+		// months without such day are skipped like RFC 5545 does
+		if t.Day() != day {
+			return nil
+		}
+		return []time.Time{t}
+	}
+
+	byDay := r.sortedByDay()
+	if len(byDay) == 0 {
+		byDay = []time.Weekday{start.Weekday()}
+	}
+	monday := day - (int(start.Weekday())+6)%7 + i*7*r.Interval
+	candidates := make([]time.Time, len(byDay))
+	for j, weekday := range byDay {
+		candidates[j] = at(year, month, monday+(int(weekday)+6)%7)
+	}
+	return candidates
+}
+
+// sortedByDay orders days from monday to sunday
+func (r *RRule) sortedByDay() []time.Weekday {
+	sorted := slices.Clone(r.ByDay)
+	slices.SortFunc(sorted, func(a, b time.Weekday) int {
+		return (int(a)+6)%7 - (int(b)+6)%7
+	})
+	return slices.Compact(sorted)
+}
@@ -0,0 +1,27 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// NewToken returns random secret token which is given to user once
+func NewToken() (string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
+}
+
+// HashToken is stored instead of token, so leaked database does not give access
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// CSRFToken is bound to session token, so it can not be made without the session and is not kept anywhere
+func CSRFToken(sessionToken string) string {
+	return HashToken("csrf:" + sessionToken)
+}
@@ -0,0 +1,188 @@
+package use_cases
+
+import (
+	"bf_me/internal/models"
+	"bf_me/internal/requests"
+	"bf_me/internal/storage"
+	"errors"
+	"fmt"
+	"slices"
+
+	"gorm.io/gorm"
+)
+
+const (
+	VisibilityPrivate      = "private"
+	VisibilityShared       = "shared"
+	VisibilityOrganization = "organization"
+)
+
+var Visibilities = []string{VisibilityPrivate, VisibilityShared, VisibilityOrganization}
+
+// kinds of content which have owners
+const (
+	KindExercise = "exercise"
+	KindBlock    = "block"
+	KindTraining = "training"
+	KindProgram  = "program"
+)
+
+var (
+	ErrNotOwner          = errors.New("only owner can change it\nclone it to make your own copy")
+	ErrInvalidVisibility = errors.New("visibility should be one of private, shared, organization")
+	ErrUserNotFound      = errors.New("user to share with is not found")
+)
+
+// Sharing is visibility of content and users it is shared with
+type Sharing struct {
+	Visibility string
+	UserIDs    []uint
+}
+
+// contentRow is what access checks need to know about any kind of content
+type contentRow struct {
+	OwnerID    *uint
+	Visibility string
+	ContentID  uint
+}
+
+type SharingUseCase struct {
+	storage *storage.Storage
+}
+
+func NewSharingUseCase(st *storage.Storage) *SharingUseCase {
+	return &SharingUseCase{storage: st}
+}
+
+func (suc *SharingUseCase) Find(user *models.User, kind string, id int) (Sharing, error) {
+	row, err := findContent(suc.storage.DB, user, kind, id)
+	if err != nil {
+		return Sharing{}, err
+	}
+	return contentSharing(suc.storage.DB, kind, row)
+}
+
+// Share sets visibility of content, for blocks and trainings it is set for the whole lineage.
+// Users are kept only for shared visibility
+func (suc *SharingUseCase) Share(user *models.User, kind string, id int, req *requests.SharingRequestBody) (Sharing, error) {
+	if !slices.Contains(Visibilities, req.Visibility) {
+		return Sharing{}, ErrInvalidVisibility
+	}
+
+	var sharing Sharing
+	err := suc.storage.DB.Transaction(func(tx *gorm.DB) error {
+		row, err := findContent(tx, user, kind, id)
+		if err != nil {
+			return err
+		}
+		if err = checkOwner(user, row.OwnerID); err != nil {
+			return err
+		}
+
+		table, column := contentTable(kind)
+		result := tx.Table(table).Where(column+" = ?", row.ContentID).Update("visibility", req.Visibility)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		result = tx.Where("kind = ? AND content_id = ?", kind, row.ContentID).Delete(&models.ContentShare{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if req.Visibility == VisibilityShared && len(req.UserIDs) != 0 {
+			userIDs := slices.Compact(slices.Sorted(slices.Values(req.UserIDs)))
+			var count int64
+			result = tx.Model(&models.User{}).Where("id IN ?", userIDs).Count(&count)
+			if result.Error != nil {
+				return result.Error
+			}
+			if int(count) != len(userIDs) {
+				return ErrUserNotFound
+			}
+
+			shares := make([]models.ContentShare, len(userIDs))
+			for i, userID := range userIDs {
+				shares[i] = models.ContentShare{Kind: kind, ContentID: row.ContentID, UserID: userID}
+			}
+			result = tx.Create(&shares)
+			if result.Error != nil {
+				return result.Error
+			}
+		}
+
+		row.Visibility = req.Visibility
+		sharing, err = contentSharing(tx, kind, row)
+		return err
+	})
+	return sharing, err
+}
+
+// visibleTo scopes content to the one user can see: admin sees everything, others see their own content,
+// trainings and programs assigned to them and published content of organization or shared with them
+func visibleTo(user *models.User, kind string) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if user.Role == RoleAdmin {
+			return db
+		}
+
+		table, column := contentTable(kind)
+		published := "TRUE"
+		if kind != KindExercise {
+			// exercises have no drafts
+			published = table + ".draft = false"
+		}
+		assigned := "FALSE"
+		if kind == KindTraining {
+			// athlete sees trainings assigned to them directly or by program
+			assigned = `trainings.id IN (SELECT COALESCE(a.training_id, pd.training_id) FROM assignments a
+				LEFT JOIN program_days pd ON pd.program_id = a.program_id
+				WHERE a.athlete_id = @user AND a.deleted_at IS NULL)`
+		}
+		if kind == KindProgram {
+			assigned = `programs.id IN (SELECT program_id FROM assignments
+				WHERE athlete_id = @user AND deleted_at IS NULL)`
+		}
+		return db.Where(fmt.Sprintf(`(%[1]s.owner_id = @user OR %[4]s OR %[3]s AND (%[1]s.visibility = @organization OR
+			%[1]s.visibility = @shared AND EXISTS (SELECT 1 FROM content_shares WHERE content_shares.kind = @kind
+				AND content_shares.content_id = %[2]s AND content_shares.user_id = @user)))`, table, column, published, assigned),
+			map[string]any{"user": user.ID, "organization": VisibilityOrganization, "shared": VisibilityShared, "kind": kind})
+	}
+}
+
+// checkOwner lets only owner or admin change content
+func checkOwner(user *models.User, ownerID *uint) error {
+	if user.Role == RoleAdmin || ownerID != nil && *ownerID == user.ID {
+		return nil
+	}
+	return ErrNotOwner
+}
+
+// contentTable returns table of content kind and its column which shares refer to
+func contentTable(kind string) (string, string) {
+	switch kind {
+	case KindBlock:
+		return "blocks", "blocks.lineage_id"
+	case KindTraining:
+		return "trainings", "trainings.lineage_id"
+	case KindProgram:
+		return "programs", "programs.id"
+	default:
+		return "exercises", "exercises.id"
+	}
+}
+
+func findContent(tx *gorm.DB, user *models.User, kind string, id int) (contentRow, error) {
+	table, column := contentTable(kind)
+	var row contentRow
+	result := tx.Table(table).Scopes(visibleTo(user, kind)).
+		Select(fmt.Sprintf("%[1]s.owner_id, %[1]s.visibility, %[2]s AS content_id", table, column)).
+		Where(table+".id = ? AND "+table+".deleted_at IS NULL", id).Take(&row)
+	return row, result.Error
+}
+
+func contentSharing(tx *gorm.DB, kind string, row contentRow) (Sharing, error) {
+	sharing := Sharing{Visibility: row.Visibility, UserIDs: make([]uint, 0)}
+	result := tx.Model(&models.ContentShare{}).Where("kind = ? AND content_id = ?", kind, row.ContentID).
+		Order("user_id").Pluck("user_id", &sharing.UserIDs)
+	return sharing, result.Error
+}
@@ -0,0 +1,232 @@
+package use_cases
+
+import (
+	"bf_me/internal/requests"
+	"bf_me/internal/storage"
+	"errors"
+	"slices"
+	"time"
+)
+
+var (
+	ErrInvalidPeriod    = errors.New("period should be week or month")
+	ErrExerciseRequired = errors.New("exerciseId is required")
+)
+
+// VolumePoint is a total of workouts started in period, durations are in seconds
+type VolumePoint struct {
+	PeriodStart time.Time
+	Workouts    uint
+	Duration    uint
+	WorkTime    uint // work time of slots which were not skipped
+	Reps        uint
+	Load        float64 // kg, sum of reps multiplied by load
+	Skipped     uint
+}
+
+// Streak is a run of consecutive days with workouts
+type Streak struct {
+	From   time.Time
+	To     time.Time
+	Length uint
+}
+
+// ProgressionPoint is a total of one exercise results in one day
+type ProgressionPoint struct {
+	Day     time.Time
+	Sets    uint
+	Reps    uint
+	MaxReps uint
+	MaxLoad float64
+	Load    float64 // kg, sum of reps multiplied by load
+}
+
+type AnalyticsUseCase struct {
+	storage *storage.Storage
+}
+
+func NewAnalyticsUseCase(st *storage.Storage) *AnalyticsUseCase {
+	return &AnalyticsUseCase{storage: st}
+}
+
+// Volume groups workouts of user by weeks or months, weeks start on Monday
+func (auc *AnalyticsUseCase) Volume(userID uint, req *requests.AnalyticsRequestBody) ([]VolumePoint, error) {
+	if !slices.Contains([]string{"week", "month"}, req.Period) {
+		return nil, ErrInvalidPeriod
+	}
+	params, err := newAnalyticsParams(userID, req)
+	if err != nil {
+		return nil, err
+	}
+	params["period"] = req.Period
+
+	points := make([]VolumePoint, 0)
+	result := auc.storage.DB.Raw(`
+		WITH logs AS (
+			SELECT l.id,
+				date_trunc(@period, l.started_at AT TIME ZONE @tz) AS period_start,
+				EXTRACT(EPOCH FROM l.finished_at - l.started_at) AS duration
+			FROM workout_logs l
+			WHERE l.user_id = @user AND l.deleted_at IS NULL
+				AND (CAST(@from AS timestamptz) IS NULL OR l.started_at >= @from)
+				AND (CAST(@to AS timestamptz) IS NULL OR l.started_at < @to)
+		), results AS (
+			SELECT r.log_id,
+				SUM(b.on_time) FILTER (WHERE NOT r.skipped) AS work_time,
+				SUM(r.reps) FILTER (WHERE NOT r.skipped) AS reps,
+				SUM(r.reps * r.load) FILTER (WHERE NOT r.skipped) AS load,
+				COUNT(*) FILTER (WHERE r.skipped) AS skipped
+			FROM workout_results r
+			JOIN blocks b ON b.id = r.block_id
+			WHERE r.log_id IN (SELECT id FROM logs)
+			GROUP BY r.log_id
+		)
+		SELECT logs.period_start,
+			COUNT(*) AS workouts,
+			COALESCE(SUM(logs.duration), 0)::bigint AS duration,
+			COALESCE(SUM(results.work_time), 0) AS work_time,
+			COALESCE(SUM(results.reps), 0) AS reps,
+			COALESCE(SUM(results.load), 0) AS load,
+			COALESCE(SUM(results.skipped), 0) AS skipped
+		FROM logs
+		LEFT JOIN results ON results.log_id = logs.id
+		GROUP BY logs.period_start
+		ORDER BY logs.period_start`, params).Scan(&points)
+	return points, result.Error
+}
+
+// Streaks returns the current and the longest streaks of days with workouts over the whole history,
+// the current one is zero if there was no workout yesterday or today
+func (auc *AnalyticsUseCase) Streaks(userID uint, req *requests.AnalyticsRequestBody) (current Streak, longest Streak, err error) {
+	params, err := newAnalyticsParams(userID, &requests.AnalyticsRequestBody{Timezone: req.Timezone})
+	if err != nil {
+		return current, longest, err
+	}
+
+	var streaks []Streak
+	result := auc.storage.DB.Raw(`
+		WITH days AS (
+			SELECT DISTINCT (started_at AT TIME ZONE @tz)::date AS day
+			FROM workout_logs
+			WHERE user_id = @user AND deleted_at IS NULL
+		), streaks AS (
+			SELECT MIN(day) AS "from", MAX(day) AS "to", COUNT(*) AS length
+			FROM (SELECT day, day - CAST(ROW_NUMBER() OVER (ORDER BY day) AS int) AS island FROM days) AS islands
+			GROUP BY island
+		)
+		(SELECT * FROM streaks ORDER BY "to" DESC LIMIT 1)
+		UNION ALL
+		(SELECT * FROM streaks ORDER BY length DESC, "to" DESC LIMIT 1)`, params).Scan(&streaks)
+	if result.Error != nil || len(streaks) != 2 {
+		return current, longest, result.Error
+	}
+
+	loc, _ := time.LoadLocation(params["tz"].(string))
+	yesterday := time.Now().In(loc).AddDate(0, 0, -1).Format(time.DateOnly)
+	if streaks[0].To.Format(time.DateOnly) >= yesterday {
+		current = streaks[0]
+	}
+	return current, streaks[1], nil
+}
+
+// Progression returns daily results of one exercise, skipped results are not counted
+func (auc *AnalyticsUseCase) Progression(userID uint, req *requests.AnalyticsRequestBody) ([]ProgressionPoint, error) {
+	if req.ExerciseID == 0 {
+		return nil, ErrExerciseRequired
+	}
+	params, err := newAnalyticsParams(userID, req)
+	if err != nil {
+		return nil, err
+	}
+	params["exercise"] = req.ExerciseID
+
+	points := make([]ProgressionPoint, 0)
+	result := auc.storage.DB.Raw(`
+		SELECT (l.started_at AT TIME ZONE @tz)::date AS day,
+			COUNT(*) AS sets,
+			COALESCE(SUM(r.reps), 0) AS reps,
+			COALESCE(MAX(r.reps), 0) AS max_reps,
+			COALESCE(MAX(r.load), 0) AS max_load,
+			COALESCE(SUM(r.reps * r.load), 0) AS load
+		FROM workout_results r
+		JOIN workout_logs l ON l.id = r.log_id
+		WHERE r.exercise_id = @exercise AND NOT r.skipped
+			AND l.user_id = @user AND l.deleted_at IS NULL
+			AND (CAST(@from AS timestamptz) IS NULL OR l.started_at >= @from)
+			AND (CAST(@to AS timestamptz) IS NULL OR l.started_at < @to)
+		GROUP BY day
+		ORDER BY day`, params).Scan(&points)
+	return points, result.Error
+}
+
+// MuscleGroups distributes work time of not skipped results by muscle groups of exercises,
+// exercise of several groups counts for each of them, so shares can sum to more than work time
+func (auc *AnalyticsUseCase) MuscleGroups(userID uint, req *requests.AnalyticsRequestBody) ([]Share, uint, error) {
+	params, err := newAnalyticsParams(userID, req)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var rows []struct {
+		Share
+		Total uint
+	}
+	result := auc.storage.DB.Raw(`
+		WITH results AS (
+			SELECT r.exercise_id, b.on_time
+			FROM workout_results r
+			JOIN workout_logs l ON l.id = r.log_id
+			JOIN blocks b ON b.id = r.block_id
+			WHERE NOT r.skipped AND l.user_id = @user AND l.deleted_at IS NULL
+				AND (CAST(@from AS timestamptz) IS NULL OR l.started_at >= @from)
+				AND (CAST(@to AS timestamptz) IS NULL OR l.started_at < @to)
+		)
+		SELECT mg.name, SUM(results.on_time) AS work_time,
+			(SELECT COALESCE(SUM(on_time), 0) FROM results) AS total
+		FROM results
+		JOIN exercises e ON e.id = results.exercise_id
+		CROSS JOIN LATERAL unnest(e.muscle_groups) AS mg(name)
+		GROUP BY mg.name
+		ORDER BY work_time DESC, mg.name`, params).Scan(&rows)
+	if result.Error != nil {
+		return nil, 0, result.Error
+	}
+
+	shares := make([]Share, len(rows))
+	var total uint
+	for i, row := range rows {
+		shares[i] = row.Share
+		total = row.Total
+	}
+	return shares, total, nil
+}
+
+// newAnalyticsParams builds named parameters shared by analytics queries, nil bounds are not limited
+func newAnalyticsParams(userID uint, req *requests.AnalyticsRequestBody) (map[string]any, error) {
+	params := map[string]any{"user": userID, "tz": "UTC", "from": nil, "to": nil}
+	if req.Timezone != "" {
+		if _, err := time.LoadLocation(req.Timezone); err != nil {
+			return nil, ErrInvalidTimezone
+		}
+		params["tz"] = req.Timezone
+	}
+
+	if req.From != "" {
+		from, err := time.Parse(time.RFC3339, req.From)
+		if err != nil {
+			return nil, ErrInvalidRange
+		}
+		params["from"] = from
+	}
+	if req.To != "" {
+		to, err := time.Parse(time.RFC3339, req.To)
+		if err != nil {
+			return nil, ErrInvalidRange
+		}
+		if from, ok := params["from"].(time.Time); ok && !to.After(from) {
+			return nil, ErrInvalidRange
+		}
+		params["to"] = to
+	}
+	return params, nil
+}
@@ -0,0 +1,166 @@
+package use_cases
+
+import (
+	"bf_me/internal/models"
+	"bf_me/internal/requests"
+	"bf_me/internal/services"
+	"bf_me/internal/storage"
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// APIKeyPrefix starts every key, so keys are told apart from session tokens
+const APIKeyPrefix = "bfk_"
+
+const (
+	ScopeRead  = "read"
+	ScopeWrite = "write"
+)
+
+// APIResources are parts of API which keys can get write scope of, like "exercises:write"
+var APIResources = []string{"exercises", "blocks", "trainings", "programs", "schedule", "runs", "logs", "assignments", "users"}
+
+var (
+	ErrInvalidScope     = errors.New("scope should be read, write or <resource>:write of exercises, blocks, trainings, programs, schedule, runs, logs, assignments, users")
+	ErrScopesRequired   = errors.New("key should have at least one scope")
+	ErrInvalidKeyName   = errors.New("key name should not be empty")
+	ErrInvalidKeyExpiry = errors.New("expiresAt should be RFC 3339 time in the future")
+)
+
+const apiKeyContextKey contextKey = "api_key"
+
+type APIKeysUseCase struct {
+	storage *storage.Storage
+}
+
+func NewAPIKeysUseCase(st *storage.Storage) *APIKeysUseCase {
+	return &APIKeysUseCase{storage: st}
+}
+
+func (auc *APIKeysUseCase) List() ([]models.APIKey, error) {
+	var keys []models.APIKey
+	result := auc.storage.DB.Preload("User").Order("id").Find(&keys)
+	return keys, result.Error
+}
+
+// Create issues key for user, the raw key is returned only here
+func (auc *APIKeysUseCase) Create(req *requests.APIKeyRequestBody) (*models.APIKey, error) {
+	if strings.TrimSpace(req.Name) == "" {
+		return nil, ErrInvalidKeyName
+	}
+	if len(req.Scopes) == 0 {
+		return nil, ErrScopesRequired
+	}
+	for _, scope := range req.Scopes {
+		if !validScope(scope) {
+			return nil, ErrInvalidScope
+		}
+	}
+	scopes := slices.Compact(slices.Sorted(slices.Values(req.Scopes)))
+
+	var expiresAt *time.Time
+	if req.ExpiresAt != "" {
+		t, err := time.Parse(time.RFC3339, req.ExpiresAt)
+		if err != nil || !t.After(time.Now()) {
+			return nil, ErrInvalidKeyExpiry
+		}
+		expiresAt = &t
+	}
+
+	var user models.User
+	result := auc.storage.DB.First(&user, req.UserID)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if user.Disabled {
+		return nil, ErrUserDisabled
+	}
+
+	token, err := services.NewToken()
+	if err != nil {
+		return nil, err
+	}
+	raw := APIKeyPrefix + token
+	key := models.APIKey{
+		Name:      strings.TrimSpace(req.Name),
+		User:      user,
+		Prefix:    raw[:len(APIKeyPrefix)+8],
+		KeyHash:   services.HashToken(raw),
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+	}
+	result = auc.storage.DB.Create(&key)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	key.Key = raw
+	return &key, nil
+}
+
+func (auc *APIKeysUseCase) Revoke(id int) error {
+	result := auc.storage.DB.Delete(&models.APIKey{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// FindAPIKey returns not expired key with its user, keys of disabled users are not valid
+func (suc *SessionsUseCase) FindAPIKey(raw string) (*models.APIKey, error) {
+	now := time.Now()
+	var key models.APIKey
+	result := suc.storage.DB.Preload("User").Where("key_hash = ? AND (expires_at IS NULL OR expires_at > ?)",
+		services.HashToken(raw), now).First(&key)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if key.User.Disabled {
+		return nil, ErrUserDisabled
+	}
+
+	// last used time is written once in a while and not on every request
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) > sessionTouchInterval {
+		key.LastUsedAt = &now
+		result = suc.storage.DB.Model(&key).Update("last_used_at", now)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+	}
+	return &key, nil
+}
+
+// APIKeyAllows checks scopes of key for reading or writing resource, write scope of resource lets read it too
+func APIKeyAllows(key *models.APIKey, resource string, write bool) bool {
+	if slices.Contains(key.Scopes, ScopeWrite) || slices.Contains(key.Scopes, resource+":"+ScopeWrite) {
+		return true
+	}
+	return !write && slices.Contains(key.Scopes, ScopeRead)
+}
+
+// WithAPIKey puts authenticated key and its user on context, such requests have no session
+func WithAPIKey(ctx context.Context, key *models.APIKey) context.Context {
+	ctx = context.WithValue(ctx, apiKeyContextKey, key)
+	return context.WithValue(ctx, userContextKey, &key.User)
+}
+
+// APIKeyFromContext returns key put by WithAPIKey, it is nil for requests with session
+func APIKeyFromContext(ctx context.Context) *models.APIKey {
+	key, _ := ctx.Value(apiKeyContextKey).(*models.APIKey)
+	return key
+}
+
+func validScope(scope string) bool {
+	if scope == ScopeRead || scope == ScopeWrite {
+		return true
+	}
+	resource, found := strings.CutSuffix(scope, ":"+ScopeWrite)
+	return found && slices.Contains(APIResources, resource)
+}
@@ -0,0 +1,321 @@
+package use_cases
+
+import (
+	"bf_me/internal/models"
+	"bf_me/internal/requests"
+	"bf_me/internal/storage"
+	"errors"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	AssignmentPending = "pending"
+	AssignmentOverdue = "overdue"
+	AssignmentDone    = "done"
+)
+
+var (
+	ErrAssignmentContent   = errors.New("assign either a training or a program")
+	ErrProgramNotPublished = errors.New("program is draft\npublish it before assigning")
+	ErrProgramEmpty        = errors.New("program has no trainings to assign")
+	ErrNotAthlete          = errors.New("trainings can be assigned to active athletes only")
+	ErrInvalidDueDate      = errors.New("due date should be like 2006-01-02")
+	ErrInvalidStatus       = errors.New("status should be one of pending, overdue, done")
+)
+
+// AssignmentProgress is an assignment with workouts athlete logged for it since it was given.
+// Training needs one workout, program needs one for every training day, so a training
+// which fills several days needs as many workouts
+type AssignmentProgress struct {
+	Assignment models.Assignment
+	Required   uint
+	Done       uint
+	LastLogAt  *time.Time
+}
+
+// Status is done when all workouts are logged, overdue when it is not done after due date
+func (ap *AssignmentProgress) Status() string {
+	if ap.Done >= ap.Required {
+		return AssignmentDone
+	}
+	if time.Now().UTC().Format(time.DateOnly) > ap.Assignment.DueDate.Format(time.DateOnly) {
+		return AssignmentOverdue
+	}
+	return AssignmentPending
+}
+
+type AssignmentsUseCase struct {
+	storage *storage.Storage
+}
+
+func NewAssignmentsUseCase(st *storage.Storage) *AssignmentsUseCase {
+	return &AssignmentsUseCase{storage: st}
+}
+
+// Mine is the feed of assignments given to athlete ordered by due date
+func (auc *AssignmentsUseCase) Mine(user *models.User, req *requests.FilterAssignmentsRequestBody) ([]AssignmentProgress, error) {
+	query := auc.storage.DB.Where("athlete_id = ?", user.ID)
+	return auc.list(query, req)
+}
+
+// Dashboard shows completion of assignments given by coach, admin sees assignments of all coaches
+func (auc *AssignmentsUseCase) Dashboard(user *models.User, req *requests.FilterAssignmentsRequestBody) ([]AssignmentProgress, error) {
+	query := auc.storage.DB
+	if user.Role != RoleAdmin {
+		query = query.Where("coach_id = ?", user.ID)
+	}
+	if req.AthleteID != 0 {
+		query = query.Where("athlete_id = ?", req.AthleteID)
+	}
+	return auc.list(query, req)
+}
+
+// Find returns assignment only to its coach, its athlete or admin
+func (auc *AssignmentsUseCase) Find(user *models.User, id int) (*AssignmentProgress, error) {
+	query := auc.storage.DB
+	if user.Role != RoleAdmin {
+		query = query.Where("coach_id = ? OR athlete_id = ?", user.ID, user.ID)
+	}
+
+	var assignment models.Assignment
+	result := assignmentsPreloads(query).First(&assignment, id)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	progress, err := assignmentsProgress(auc.storage.DB, []models.Assignment{assignment})
+	if err != nil {
+		return nil, err
+	}
+	return &progress[0], nil
+}
+
+func (auc *AssignmentsUseCase) Create(user *models.User, req *requests.AssignmentRequestBody) (*AssignmentProgress, error) {
+	if (req.TrainingID == 0) == (req.ProgramID == 0) {
+		return nil, ErrAssignmentContent
+	}
+	if req.DueDate == "" {
+		return nil, ErrInvalidDueDate
+	}
+
+	assignment := models.Assignment{CoachID: user.ID}
+	err := auc.storage.DB.Transaction(func(tx *gorm.DB) error {
+		var athlete models.User
+		result := tx.Where("role = ? AND disabled = false", RoleAthlete).First(&athlete, req.AthleteID)
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return ErrNotAthlete
+		}
+		if result.Error != nil {
+			return result.Error
+		}
+		assignment.AthleteID = athlete.ID
+
+		if req.TrainingID != 0 {
+			var training models.Training
+			result = tx.Scopes(visibleTo(user, KindTraining)).First(&training, req.TrainingID)
+			if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+				return ErrTrainingDeleted
+			}
+			if result.Error != nil {
+				return result.Error
+			}
+			if training.Draft {
+				return ErrTrainingNotPublished
+			}
+			assignment.TrainingID = &training.ID
+		} else {
+			var program models.Program
+			result = tx.Scopes(visibleTo(user, KindProgram)).First(&program, req.ProgramID)
+			if result.Error != nil {
+				return result.Error
+			}
+			if program.Draft {
+				return ErrProgramNotPublished
+			}
+			var days int64
+			result = tx.Model(&models.ProgramDay{}).Where("program_id = ? AND training_id IS NOT NULL", program.ID).Count(&days)
+			if result.Error != nil {
+				return result.Error
+			}
+			if days == 0 {
+				return ErrProgramEmpty
+			}
+			assignment.ProgramID = &program.ID
+		}
+
+		if err := applyAssignment(&assignment, req); err != nil {
+			return err
+		}
+		return tx.Create(&assignment).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return auc.Find(user, int(assignment.ID))
+}
+
+// Update changes due date and notes, only coach who gave assignment or admin can do it
+func (auc *AssignmentsUseCase) Update(user *models.User, id int, req *requests.AssignmentRequestBody) (*AssignmentProgress, error) {
+	assignment, err := auc.owned(user, id)
+	if err != nil {
+		return nil, err
+	}
+	if err = applyAssignment(assignment, req); err != nil {
+		return nil, err
+	}
+
+	result := auc.storage.DB.Model(assignment).Updates(map[string]any{"due_date": assignment.DueDate, "notes": assignment.Notes})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return auc.Find(user, id)
+}
+
+func (auc *AssignmentsUseCase) Delete(user *models.User, id int) error {
+	assignment, err := auc.owned(user, id)
+	if err != nil {
+		return err
+	}
+	return auc.storage.DB.Delete(assignment).Error
+}
+
+// owned finds assignment which user can change, athlete can see it but not change
+func (auc *AssignmentsUseCase) owned(user *models.User, id int) (*models.Assignment, error) {
+	query := auc.storage.DB
+	if user.Role != RoleAdmin {
+		query = query.Where("coach_id = ? OR athlete_id = ?", user.ID, user.ID)
+	}
+
+	var assignment models.Assignment
+	result := query.First(&assignment, id)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if err := checkOwner(user, &assignment.CoachID); err != nil {
+		return nil, err
+	}
+	return &assignment, nil
+}
+
+func (auc *AssignmentsUseCase) list(query *gorm.DB, req *requests.FilterAssignmentsRequestBody) ([]AssignmentProgress, error) {
+	if req.Status != "" && !slices.Contains([]string{AssignmentPending, AssignmentOverdue, AssignmentDone}, req.Status) {
+		return nil, ErrInvalidStatus
+	}
+	if req.From != "" {
+		from, err := time.Parse(time.DateOnly, req.From)
+		if err != nil {
+			return nil, ErrInvalidRange
+		}
+		query = query.Where("due_date >= ?", from)
+	}
+	if req.To != "" {
+		to, err := time.Parse(time.DateOnly, req.To)
+		if err != nil {
+			return nil, ErrInvalidRange
+		}
+		query = query.Where("due_date <= ?", to)
+	}
+
+	var assignments []models.Assignment
+	result := assignmentsPreloads(query).Order("due_date, id").Find(&assignments)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	progress, err := assignmentsProgress(auc.storage.DB, assignments)
+	if err != nil {
+		return nil, err
+	}
+	if req.Status != "" {
+		progress = slices.DeleteFunc(progress, func(ap AssignmentProgress) bool {
+			return ap.Status() != req.Status
+		})
+	}
+	return progress, nil
+}
+
+func applyAssignment(assignment *models.Assignment, req *requests.AssignmentRequestBody) error {
+	if req.DueDate != "" {
+		dueDate, err := time.Parse(time.DateOnly, req.DueDate)
+		if err != nil {
+			return ErrInvalidDueDate
+		}
+		assignment.DueDate = dueDate
+	}
+	if req.Notes != nil {
+		assignment.Notes = *req.Notes
+	}
+	return nil
+}
+
+// assignmentsPreloads loads people and content of assignments, even deleted content keeps its title
+func assignmentsPreloads(query *gorm.DB) *gorm.DB {
+	unscoped := func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped()
+	}
+	return query.Preload("Coach").Preload("Athlete").Preload("Training", unscoped).
+		Preload("Program", unscoped).Preload("Program.Days")
+}
+
+// assignmentsProgress counts workouts logged by athletes since assignments were given.
+// A log of any version of assigned training counts, as trainings are edited into new versions,
+// but no more times than the training lineage fills days, so repeating one day does not finish a program
+func assignmentsProgress(db *gorm.DB, assignments []models.Assignment) ([]AssignmentProgress, error) {
+	progress := make([]AssignmentProgress, len(assignments))
+	ids := make([]uint, len(assignments))
+	for i, a := range assignments {
+		progress[i] = AssignmentProgress{Assignment: a, Required: 1}
+		if a.Program != nil {
+			progress[i].Required = 0
+			for _, d := range a.Program.Days {
+				if d.TrainingID != nil {
+					progress[i].Required++
+				}
+			}
+		}
+		ids[i] = a.ID
+	}
+	if len(ids) == 0 {
+		return progress, nil
+	}
+
+	var rows []struct {
+		ID        uint
+		Done      uint
+		LastLogAt *time.Time
+	}
+	result := db.Raw(`
+		SELECT a.id, CAST(SUM(LEAST(d.required, l.done)) AS bigint) AS done, MAX(l.last_log_at) AS last_log_at
+		FROM assignments a
+		JOIN LATERAL (
+			SELECT t.lineage_id, COUNT(*) AS required FROM trainings t
+			WHERE t.id = a.training_id
+			GROUP BY t.lineage_id
+			UNION ALL
+			SELECT t.lineage_id, COUNT(*) AS required FROM program_days pd
+			JOIN trainings t ON t.id = pd.training_id
+			WHERE pd.program_id = a.program_id
+			GROUP BY t.lineage_id
+		) d ON TRUE
+		JOIN LATERAL (
+			SELECT COUNT(*) AS done, MAX(l.started_at) AS last_log_at FROM workout_logs l
+			JOIN trainings versions ON versions.id = l.training_id
+			WHERE versions.lineage_id = d.lineage_id AND l.user_id = a.athlete_id
+				AND l.started_at >= a.created_at AND l.deleted_at IS NULL
+		) l ON TRUE
+		WHERE a.id IN ?
+		GROUP BY a.id`, ids).Scan(&rows)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	for _, row := range rows {
+		i := slices.IndexFunc(progress, func(ap AssignmentProgress) bool {
+			return ap.Assignment.ID == row.ID
+		})
+		progress[i].Done = min(row.Done, progress[i].Required)
+		progress[i].LastLogAt = row.LastLogAt
+	}
+	return progress, nil
+}
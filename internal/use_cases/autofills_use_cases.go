@@ -0,0 +1,154 @@
+package use_cases
+
+import (
+	"bf_me/internal/models"
+	"bf_me/internal/requests"
+	"errors"
+	"math/rand"
+	"slices"
+
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+)
+
+var ErrEmptyPool = errors.New("no exercises match the pool\nchoose tags, muscle groups or exercises")
+
+// Autofill fills remaining capacity of block with exercises of the pool.
+// Pool exercises go in order of ids or of the explicit list unless seed shuffles them,
+// they are taken again and again until block is full unless NoRepeats is set
+func (buc *BlocksUseCase) Autofill(user *models.User, blockID uint, req *requests.AutofillBlockRequestBody) (models.Block, error) {
+	if err := checkMuscleGroups(req.MuscleGroups); err != nil {
+		return models.Block{}, err
+	}
+
+	var block models.Block
+	err := buc.storage.DB.Transaction(func(tx *gorm.DB) error {
+		ebs, err := buc.lockBlockExercises(tx, user, &block, blockID)
+		if err != nil {
+			return err
+		}
+		if len(ebs) >= blockCapacity(&block) {
+			return ErrBlockFullOfExercises
+		}
+
+		pool, err := autofillPool(tx, user, req)
+		if err != nil {
+			return err
+		}
+		if req.Seed != nil {
+			r := rand.New(rand.NewSource(*req.Seed))
+			r.Shuffle(len(pool), func(i, j int) {
+				pool[i], pool[j] = pool[j], pool[i]
+			})
+		}
+
+		ebs, err = buc.editableBlockExercises(tx, &block, ebs)
+		if err != nil {
+			return err
+		}
+
+		used := make(map[uint]bool, len(ebs))
+		for _, eb := range ebs {
+			used[eb.ExerciseID] = true
+		}
+		side := autofillFirstSide(ebs)
+		free := blockCapacity(&block) - len(ebs)
+		order := buc.findNextOrder(ebs)
+
+		for added := true; free > 0 && added; {
+			// the whole pool is walked on every pass, nothing added means nothing else fits
+			added = false
+			for i := range pool {
+				exercise := &pool[i]
+				if free == 0 {
+					break
+				}
+				if (req.NoRepeats && used[exercise.ID]) || exerciseSlots(exercise) > free {
+					continue
+				}
+
+				slotSide := ""
+				if exercise.Unilateral && req.AlternateSides {
+					slotSide = side
+					side = oppositeSide(side)
+				}
+				created, err := buc.createSlots(tx, exercise, block.ID, order, slotSide)
+				if err != nil {
+					return err
+				}
+
+				used[exercise.ID] = true
+				order += uint(len(created))
+				free -= len(created)
+				added = true
+			}
+			if req.NoRepeats {
+				break
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return block, err
+	}
+
+	result := buc.storage.DB.Preload("ExerciseBlocks").Preload("Exercises.Tags").First(&block, block.ID)
+	return block, result.Error
+}
+
+// autofillPool finds exercises visible to user of the explicit list in its order,
+// or exercises having any of tags or muscle groups ordered by id
+func autofillPool(tx *gorm.DB, user *models.User, req *requests.AutofillBlockRequestBody) ([]models.Exercise, error) {
+	var exercises []models.Exercise
+	if len(req.ExerciseIDs) != 0 {
+		result := tx.Scopes(visibleTo(user, KindExercise)).Where("id IN ?", req.ExerciseIDs).Find(&exercises)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+
+		pool := make([]models.Exercise, 0, len(req.ExerciseIDs))
+		for _, id := range req.ExerciseIDs {
+			index := slices.IndexFunc(exercises, func(e models.Exercise) bool {
+				return e.ID == id
+			})
+			if index == -1 {
+				return nil, ErrExerciseDeleted
+			}
+			pool = append(pool, exercises[index])
+		}
+		return pool, nil
+	}
+
+	if len(req.TagIDs) == 0 && len(req.MuscleGroups) == 0 {
+		return nil, ErrEmptyPool
+	}
+
+	tagged := tx.Table("exercises_tags").Select("exercise_id").Where("tag_id IN ?", req.TagIDs)
+	condition := tx.Where("id IN (?)", tagged)
+	if len(req.TagIDs) == 0 {
+		condition = tx.Where("muscle_groups && ?", pq.StringArray(req.MuscleGroups))
+	} else if len(req.MuscleGroups) != 0 {
+		condition = condition.Or("muscle_groups && ?", pq.StringArray(req.MuscleGroups))
+	}
+	result := tx.Scopes(visibleTo(user, KindExercise)).Where(condition).Order("id").Find(&exercises)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if len(exercises) == 0 {
+		return nil, ErrEmptyPool
+	}
+	return exercises, nil
+}
+
+// autofillFirstSide continues alternation after the last pair of block
+func autofillFirstSide(ebs []models.ExerciseBlock) string {
+	for i := len(ebs) - 1; i >= 0; i-- {
+		pairIndex := findPairIndex(ebs, i)
+		if pairIndex == -1 {
+			continue
+		}
+		first := min(i, pairIndex)
+		return oppositeSide(ebs[first].Side)
+	}
+	return "left"
+}
@@ -7,31 +7,43 @@ import (
 	"errors"
 	"fmt"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"math"
 	"slices"
 )
 
 var (
-	ErrBlockNotReady        = errors.New("block is not ready to be published\nadd more exercises")
-	ErrBlockCannotBeDeleted = errors.New("block cannot be deleted becase it is a part of workout")
-	ErrBlockFullOfExercises = errors.New("block full of exercises\n check it and be ready to publish it")
-	ErrExerciseDeleted      = errors.New("exercise was deleted\nchoose another one")
+	ErrBlockNotReady          = errors.New("block is not ready to be published\nadd more exercises")
+	ErrBlockCannotBeDeleted   = errors.New("block cannot be deleted becase it is a part of workout")
+	ErrBlockFullOfExercises   = errors.New("block full of exercises\n check it and be ready to publish it")
+	ErrExerciseDeleted        = errors.New("exercise was deleted\nchoose another one")
+	ErrSlotPositionOutOfRange = errors.New("position is out of range")
+	ErrInvalidOrder           = errors.New("order should list every current position exactly once")
+	ErrInvalidSide            = errors.New("side should be one of left, right or empty")
+	ErrPairedSlot             = errors.New("slot is paired with the other side\nit should keep unilateral exercise and left or right side")
+	ErrBilateralSide          = errors.New("only unilateral exercise can have left or right side")
+	ErrBlockTitleTaken        = errors.New("block with such title already exists\nchoose another title")
 )
 
 type BlocksUseCase struct {
-	storage *storage.Storage
+	storage     *storage.Storage
+	validations *ValidationsUseCase
 }
 
 func NewBlocksUseCase(st *storage.Storage) *BlocksUseCase {
-	return &BlocksUseCase{storage: st}
+	return &BlocksUseCase{storage: st, validations: NewValidationsUseCase(st)}
 }
 
-func (buc *BlocksUseCase) List(req *requests.FilterRequestBody) ([]models.Block, error) {
+// List returns blocks visible to user
+func (buc *BlocksUseCase) List(user *models.User, req *requests.FilterRequestBody) ([]models.Block, error) {
 	var blocks []models.Block
 	updatedAtSql := fmt.Sprintf("updated_at %s", req.UpdatedAt)
+	query := buc.storage.DB.Scopes(visibleTo(user, KindBlock))
 
 	if req.Suggestion != "" {
-		result := buc.storage.DB.Where("title_en ILIKE ? OR title_ru ILIKE ?", "%"+req.Suggestion+"%", "%"+req.Suggestion+"%").Find(&blocks)
+		// slots are loaded for summaries of suggested blocks
+		result := query.Where("title_en ILIKE ? OR title_ru ILIKE ?", "%"+req.Suggestion+"%", "%"+req.Suggestion+"%").
+			Preload("ExerciseBlocks").Preload("Exercises.Tags").Find(&blocks)
 		return blocks, result.Error
 	}
 
@@ -45,80 +57,381 @@ func (buc *BlocksUseCase) List(req *requests.FilterRequestBody) ([]models.Block,
 			whereClause = "draft = false"
 		}
 
-		result := buc.storage.DB.Where(whereClause).Order(updatedAtSql).Preload("ExerciseBlocks").Preload("Exercises").Find(&blocks)
+		result := query.Where(whereClause).Order(updatedAtSql).Preload("ExerciseBlocks").Preload("Exercises.Tags").Find(&blocks)
 		return blocks, result.Error
 	}
 
-	result := buc.storage.DB.Order(updatedAtSql).Preload("ExerciseBlocks").Preload("Exercises").Find(&blocks)
+	result := query.Order(updatedAtSql).Preload("ExerciseBlocks").Preload("Exercises.Tags").Find(&blocks)
 	return blocks, result.Error
 }
 
-func (buc *BlocksUseCase) AddBlockExercise(blockID, exerciseID uint, req *requests.AddBlockExerciseRequestBody) (models.Block, error) {
+func (buc *BlocksUseCase) AddBlockExercise(user *models.User, blockID, exerciseID uint, req *requests.AddBlockExerciseRequestBody) (models.Block, error) {
+	return buc.insertBlockExercise(user, blockID, exerciseID, req.Side, nil)
+}
+
+func (buc *BlocksUseCase) InsertBlockExercise(user *models.User, blockID, exerciseID uint, req *requests.InsertBlockExerciseRequestBody) (models.Block, error) {
+	return buc.insertBlockExercise(user, blockID, exerciseID, req.Side, &req.Position)
+}
+
+// insertBlockExercise puts exercise at given position and shifts following slots,
+// nil position appends exercise to the end of block.
+// Unilateral exercise takes two linked slots, the one of given side goes first
+func (buc *BlocksUseCase) insertBlockExercise(user *models.User, blockID, exerciseID uint, side string, position *uint) (models.Block, error) {
 	var block models.Block
-	result := buc.storage.DB.Preload("ExerciseBlocks").First(&block, blockID)
-	if result.Error != nil {
-		return block, result.Error
+	err := buc.storage.DB.Transaction(func(tx *gorm.DB) error {
+		ebs, err := buc.lockBlockExercises(tx, user, &block, blockID)
+		if err != nil {
+			return err
+		}
+		if !slices.Contains([]string{"right", "left", ""}, side) {
+			return ErrInvalidSide
+		}
+		if position != nil && int(*position) > len(ebs) {
+			return ErrSlotPositionOutOfRange
+		}
+
+		var exercise models.Exercise
+		result := tx.Scopes(visibleTo(user, KindExercise)).First(&exercise, exerciseID)
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return ErrExerciseDeleted
+		}
+		if result.Error != nil {
+			return result.Error
+		}
+
+		ebs, err = buc.editableBlockExercises(tx, &block, ebs)
+		if err != nil {
+			return err
+		}
+
+		//check if exercises count is not reached its highest level
+		block.ExerciseBlocks = ebs
+		full := buc.checkBlockFullOfExercises(&block)
+		if full || (exercise.Unilateral && len(ebs)+2 > blockCapacity(&block)) {
+			return ErrBlockFullOfExercises
+		}
+
+		created, err := buc.createSlots(tx, &exercise, block.ID, buc.findNextOrder(ebs), side)
+		if err != nil {
+			return err
+		}
+		if position == nil {
+			return nil
+		}
+
+		ebs = slices.Insert(ebs, int(*position), created...)
+		return buc.renumberBlockExercises(tx, ebs)
+	})
+	if err != nil {
+		return block, err
 	}
-	if !block.Draft {
-		return block, errors.New("block is not draft\nyou cannot add exercise")
+
+	result := buc.storage.DB.Preload("ExerciseBlocks").Preload("Exercises.Tags").First(&block, block.ID)
+	return block, result.Error
+}
+
+// createSlots creates one slot or linked left+right pair for unilateral exercise
+func (buc *BlocksUseCase) createSlots(tx *gorm.DB, exercise *models.Exercise, blockID, order uint, side string) ([]models.ExerciseBlock, error) {
+	first := models.ExerciseBlock{
+		ExerciseID:    exercise.ID,
+		BlockID:       blockID,
+		ExerciseOrder: order,
+		Side:          side,
 	}
-	//check if exercises count is not reached its highest level
-	full := buc.checkBlockFullOfExercises(&block)
-	if full {
-		return block, ErrBlockFullOfExercises
+	if !exercise.Unilateral {
+		result := tx.Create(&first)
+		return []models.ExerciseBlock{first}, result.Error
 	}
 
-	var exercise models.Exercise
-	result = buc.storage.DB.First(&exercise, exerciseID)
-	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-		return block, ErrExerciseDeleted
+	if first.Side == "" {
+		first.Side = "left"
 	}
+	result := tx.Create(&first)
 	if result.Error != nil {
-		return block, result.Error
+		return nil, result.Error
 	}
 
-	var ebs []models.ExerciseBlock
-	result = buc.storage.DB.Where("block_id = ?", blockID).Find(&ebs)
+	second := models.ExerciseBlock{
+		ExerciseID:    exercise.ID,
+		BlockID:       blockID,
+		ExerciseOrder: order + 1,
+		Side:          oppositeSide(first.Side),
+		PairSlotID:    &first.ID,
+	}
+	result = tx.Create(&second)
 	if result.Error != nil {
-		return block, result.Error
+		return nil, result.Error
 	}
 
-	nextOrder := buc.findNextOrder(block.ExerciseBlocks)
-	side := ""
-	if slices.Contains([]string{"right", "left", ""}, req.Side) {
-		side = req.Side
+	first.PairSlotID = &second.ID
+	result = tx.Model(&first).Update("pair_slot_id", second.ID)
+	return []models.ExerciseBlock{first, second}, result.Error
+}
+
+// RemoveBlockExercise removes the first slot with given exercise, use RemoveBlockSlot to be precise
+func (buc *BlocksUseCase) RemoveBlockExercise(user *models.User, blockID, exerciseID uint) (models.Block, error) {
+	return buc.removeBlockSlot(user, blockID, func(eb models.ExerciseBlock) bool {
+		return eb.ExerciseID == exerciseID
+	})
+}
+
+func (buc *BlocksUseCase) RemoveBlockSlot(user *models.User, blockID, slotID uint) (models.Block, error) {
+	return buc.removeBlockSlot(user, blockID, func(eb models.ExerciseBlock) bool {
+		return eb.ID == slotID
+	})
+}
+
+// removeBlockSlot removes the first matching slot together with its pair slot
+func (buc *BlocksUseCase) removeBlockSlot(user *models.User, blockID uint, match func(eb models.ExerciseBlock) bool) (models.Block, error) {
+	var block models.Block
+	err := buc.storage.DB.Transaction(func(tx *gorm.DB) error {
+		ebs, err := buc.lockBlockExercises(tx, user, &block, blockID)
+		if err != nil {
+			return err
+		}
+
+		index := slices.IndexFunc(ebs, match)
+		if index == -1 {
+			return gorm.ErrRecordNotFound
+		}
+		ebs, err = buc.editableBlockExercises(tx, &block, ebs)
+		if err != nil {
+			return err
+		}
+
+		removed, rest := splitPair(ebs, index)
+		result := tx.Unscoped().Delete(&removed)
+		if result.Error != nil {
+			return result.Error
+		}
+		return buc.renumberBlockExercises(tx, rest)
+	})
+	if err != nil {
+		return block, err
 	}
-	eb := models.ExerciseBlock{
-		ExerciseID:    exerciseID,
-		BlockID:       blockID,
-		ExerciseOrder: nextOrder,
-		Side:          side,
+
+	result := buc.storage.DB.Preload("ExerciseBlocks").Preload("Exercises.Tags").First(&block, block.ID)
+	return block, result.Error
+}
+
+// UpdateBlockSlot changes exercise or side of slot, changes of paired slot are mirrored to its pair slot.
+// Single slot which gets unilateral exercise gets pair slot of the other side right after it
+func (buc *BlocksUseCase) UpdateBlockSlot(user *models.User, blockID, slotID uint, req *requests.UpdateSlotRequestBody) (models.Block, error) {
+	var block models.Block
+	err := buc.storage.DB.Transaction(func(tx *gorm.DB) error {
+		ebs, err := buc.lockBlockExercises(tx, user, &block, blockID)
+		if err != nil {
+			return err
+		}
+
+		index := slices.IndexFunc(ebs, func(eb models.ExerciseBlock) bool {
+			return eb.ID == slotID
+		})
+		if index == -1 {
+			return gorm.ErrRecordNotFound
+		}
+		ebs, err = buc.editableBlockExercises(tx, &block, ebs)
+		if err != nil {
+			return err
+		}
+		eb := ebs[index]
+		var pair *models.ExerciseBlock
+		if pairIndex := findPairIndex(ebs, index); pairIndex != -1 {
+			pair = &ebs[pairIndex]
+		}
+
+		var exercise models.Exercise
+		if req.ExerciseID != 0 {
+			result := tx.Scopes(visibleTo(user, KindExercise)).First(&exercise, req.ExerciseID)
+			if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+				return ErrExerciseDeleted
+			}
+			if result.Error != nil {
+				return result.Error
+			}
+			if pair != nil && !exercise.Unilateral {
+				return ErrPairedSlot
+			}
+			eb.ExerciseID = req.ExerciseID
+			if pair != nil {
+				pair.ExerciseID = req.ExerciseID
+			}
+		} else {
+			// unscoped as the current exercise may be deleted after being added
+			result := tx.Unscoped().First(&exercise, eb.ExerciseID)
+			if result.Error != nil {
+				return result.Error
+			}
+		}
+		if req.Side != nil {
+			if !slices.Contains([]string{"right", "left", ""}, *req.Side) {
+				return ErrInvalidSide
+			}
+			if pair != nil && *req.Side == "" {
+				return ErrPairedSlot
+			}
+			eb.Side = *req.Side
+			if pair != nil {
+				pair.Side = oppositeSide(eb.Side)
+			}
+		}
+		if !exercise.Unilateral && eb.Side != "" {
+			if req.Side != nil {
+				return ErrBilateralSide
+			}
+			eb.Side = ""
+		}
+
+		if pair != nil {
+			result := tx.Save(pair)
+			if result.Error != nil {
+				return result.Error
+			}
+		}
+		if pair != nil || !exercise.Unilateral {
+			return tx.Save(&eb).Error
+		}
+		return buc.pairSlot(tx, &block, ebs, index, eb)
+	})
+	if err != nil {
+		return block, err
 	}
-	result = buc.storage.DB.Create(&eb)
+
+	result := buc.storage.DB.Preload("ExerciseBlocks").Preload("Exercises.Tags").First(&block, block.ID)
+	return block, result.Error
+}
+
+// pairSlot saves single slot at index of ebs with unilateral exercise and creates its pair slot right after it
+func (buc *BlocksUseCase) pairSlot(tx *gorm.DB, block *models.Block, ebs []models.ExerciseBlock, index int, eb models.ExerciseBlock) error {
+	if len(ebs)+1 > blockCapacity(block) {
+		return ErrBlockFullOfExercises
+	}
+	if eb.Side == "" {
+		eb.Side = "left"
+	}
+	result := tx.Save(&eb)
 	if result.Error != nil {
-		return block, result.Error
+		return result.Error
 	}
 
-	result = buc.storage.DB.Preload("ExerciseBlocks").Preload("Exercises").First(&block, blockID)
+	pair := models.ExerciseBlock{
+		ExerciseID:    eb.ExerciseID,
+		BlockID:       eb.BlockID,
+		ExerciseOrder: buc.findNextOrder(ebs),
+		Side:          oppositeSide(eb.Side),
+		PairSlotID:    &eb.ID,
+	}
+	result = tx.Create(&pair)
+	if result.Error != nil {
+		return result.Error
+	}
+	result = tx.Model(&eb).Update("pair_slot_id", pair.ID)
+	if result.Error != nil {
+		return result.Error
+	}
+	return buc.renumberBlockExercises(tx, slices.Insert(ebs, index+1, pair))
+}
+
+// MoveBlockExercise moves slot to new position, pair slot is moved along with it
+func (buc *BlocksUseCase) MoveBlockExercise(user *models.User, blockID uint, req *requests.MoveSlotRequestBody) (models.Block, error) {
+	var block models.Block
+	err := buc.storage.DB.Transaction(func(tx *gorm.DB) error {
+		ebs, err := buc.lockBlockExercises(tx, user, &block, blockID)
+		if err != nil {
+			return err
+		}
+		if int(req.From) >= len(ebs) || int(req.To) >= len(ebs) {
+			return ErrSlotPositionOutOfRange
+		}
+		ebs, err = buc.editableBlockExercises(tx, &block, ebs)
+		if err != nil {
+			return err
+		}
+
+		moved, rest := splitPair(ebs, int(req.From))
+		to := min(int(req.To), len(rest))
+		return buc.renumberBlockExercises(tx, slices.Insert(rest, to, moved...))
+	})
+	if err != nil {
+		return block, err
+	}
+
+	result := buc.storage.DB.Preload("ExerciseBlocks").Preload("Exercises.Tags").First(&block, block.ID)
 	return block, result.Error
 }
 
-func (buc *BlocksUseCase) RemoveBlockExercise(blockID, exerciseID uint) (models.Block, error) {
-	var exerciseBlockRelation models.ExerciseBlock
+// ReorderBlockExercises sets the whole order at once,
+// req.Order lists current positions of slots in the new order
+func (buc *BlocksUseCase) ReorderBlockExercises(user *models.User, blockID uint, req *requests.ReorderSlotsRequestBody) (models.Block, error) {
 	var block models.Block
+	err := buc.storage.DB.Transaction(func(tx *gorm.DB) error {
+		ebs, err := buc.lockBlockExercises(tx, user, &block, blockID)
+		if err != nil {
+			return err
+		}
+		ebs, err = buc.editableBlockExercises(tx, &block, ebs)
+		if err != nil {
+			return err
+		}
 
-	result := buc.storage.DB.First(&exerciseBlockRelation, "block_id = ? AND exercise_id = ?", blockID, exerciseID)
-	if result.Error != nil {
-		return block, result.Error
+		reordered, err := reorder(ebs, req.Order)
+		if err != nil {
+			return err
+		}
+		return buc.renumberBlockExercises(tx, keepPairsTogether(reordered))
+	})
+	if err != nil {
+		return block, err
 	}
 
-	result = buc.storage.DB.Unscoped().Delete(&exerciseBlockRelation)
+	result := buc.storage.DB.Preload("ExerciseBlocks").Preload("Exercises.Tags").First(&block, block.ID)
+	return block, result.Error
+}
+
+// lockBlockExercises locks block row of user until the end of transaction
+// and returns its slots sorted by order
+func (buc *BlocksUseCase) lockBlockExercises(tx *gorm.DB, user *models.User, block *models.Block, blockID uint) ([]models.ExerciseBlock, error) {
+	result := tx.Scopes(visibleTo(user, KindBlock)).Clauses(clause.Locking{Strength: "UPDATE"}).First(block, blockID)
 	if result.Error != nil {
-		return block, result.Error
+		return nil, result.Error
+	}
+	if err := checkOwner(user, block.OwnerID); err != nil {
+		return nil, err
 	}
 
-	result = buc.storage.DB.Preload("ExerciseBlocks").Preload("Exercises").First(&block, blockID)
-	return block, result.Error
+	var ebs []models.ExerciseBlock
+	result = tx.Where("block_id = ?", blockID).Order("exercise_order, id").Find(&ebs)
+	return ebs, result.Error
+}
+
+// editableBlockExercises switches published block to its new draft version,
+// returned slots of the new version are in the same order as given ones
+func (buc *BlocksUseCase) editableBlockExercises(tx *gorm.DB, block *models.Block, ebs []models.ExerciseBlock) ([]models.ExerciseBlock, error) {
+	if block.Draft {
+		return ebs, nil
+	}
+
+	block.ExerciseBlocks = ebs
+	draft, err := editableBlock(tx, block)
+	if err != nil {
+		return nil, err
+	}
+	*block = draft
+	return draft.ExerciseBlocks, nil
+}
+
+// renumberBlockExercises stores slots order without gaps starting from 0
+func (buc *BlocksUseCase) renumberBlockExercises(tx *gorm.DB, ebs []models.ExerciseBlock) error {
+	for i, eb := range ebs {
+		if eb.ExerciseOrder == uint(i) {
+			continue
+		}
+		result := tx.Model(&models.ExerciseBlock{}).Where("id = ?", eb.ID).Update("exercise_order", uint(i))
+		if result.Error != nil {
+			return result.Error
+		}
+	}
+	return nil
 }
 
 func (buc *BlocksUseCase) findNextOrder(ebs []models.ExerciseBlock) uint {
@@ -132,7 +445,8 @@ func (buc *BlocksUseCase) findNextOrder(ebs []models.ExerciseBlock) uint {
 	return order
 }
 
-func (buc *BlocksUseCase) updateBlock(block models.Block, req requests.BlockRequestBody) (models.Block, error) {
+// updateBlock copies request into block, titles should stay unique among blocks of other lineages
+func (buc *BlocksUseCase) updateBlock(tx *gorm.DB, block models.Block, req requests.BlockRequestBody) (models.Block, error) {
 	if req.TitleRu != "" {
 		block.TitleRu = req.TitleRu
 	}
@@ -148,18 +462,35 @@ func (buc *BlocksUseCase) updateBlock(block models.Block, req requests.BlockRequ
 	}
 	block.RelaxTime = req.RelaxTime
 
+	// versions of one lineage share titles, so only other lineages are checked, deleted ones too
+	var count int64
+	result := tx.Unscoped().Model(&models.Block{}).
+		Where("(title_en = ? OR title_ru = ?) AND lineage_id <> ?", block.TitleEn, block.TitleRu, block.LineageID).Count(&count)
+	if result.Error != nil {
+		return block, result.Error
+	}
+	if count != 0 {
+		return block, ErrBlockTitleTaken
+	}
 	return block, nil
 }
 
-func (buc *BlocksUseCase) Create(req *requests.BlockRequestBody) (models.Block, error) {
-	var block models.Block
-	updatedBlock, err := buc.updateBlock(block, *req)
+// Create makes private block owned by user
+func (buc *BlocksUseCase) Create(user *models.User, req *requests.BlockRequestBody) (models.Block, error) {
+	block := models.Block{OwnerID: &user.ID, Visibility: VisibilityPrivate}
+	updatedBlock, err := buc.updateBlock(buc.storage.DB, block, *req)
 	if err != nil {
 		return block, err
 	}
 	buc.fitTiming(&updatedBlock)
 
 	result := buc.storage.DB.Create(&updatedBlock)
+	if result.Error != nil {
+		return updatedBlock, result.Error
+	}
+
+	updatedBlock.LineageID = updatedBlock.ID
+	result = buc.storage.DB.Model(&updatedBlock).Update("lineage_id", updatedBlock.LineageID)
 	return updatedBlock, result.Error
 }
 
@@ -195,49 +526,131 @@ func (buc *BlocksUseCase) fitTiming(block *models.Block) {
 	block.RelaxTime = 60 - block.OnTime
 }
 
-func (buc *BlocksUseCase) Find(id int) (models.Block, error) {
+func (buc *BlocksUseCase) Find(user *models.User, id int) (models.Block, error) {
 	var block models.Block
-	result := buc.storage.DB.Preload("ExerciseBlocks").Preload("Exercises").First(&block, id)
+	result := buc.storage.DB.Scopes(visibleTo(user, KindBlock)).Preload("ExerciseBlocks").Preload("Exercises.Tags").First(&block, id)
 	return block, result.Error
 }
 
-func (buc *BlocksUseCase) Update(id int, req *requests.BlockRequestBody) (models.Block, error) {
+// Update changes draft block, published block is frozen so a new draft version is created and changed
+func (buc *BlocksUseCase) Update(user *models.User, id int, req *requests.BlockRequestBody) (models.Block, error) {
 	var block models.Block
-	result := buc.storage.DB.Preload("ExerciseBlocks").Preload("Exercises").First(&block, id)
-	if result.Error != nil {
-		return block, result.Error
-	}
+	err := buc.storage.DB.Transaction(func(tx *gorm.DB) error {
+		ebs, err := buc.lockBlockExercises(tx, user, &block, uint(id))
+		if err != nil {
+			return err
+		}
+		if _, err = buc.editableBlockExercises(tx, &block, ebs); err != nil {
+			return err
+		}
+
+		buc.fitTiming(&block)
+		updatedBlock, err := buc.updateBlock(tx, block, *req)
+		if err != nil {
+			return err
+		}
 
-	buc.fitTiming(&block)
-	updatedBlock, err := buc.updateBlock(block, *req)
+		block = updatedBlock
+		block.ExerciseBlocks = nil
+		return tx.Omit(clause.Associations).Save(&block).Error
+	})
 	if err != nil {
 		return block, err
 	}
 
-	result = buc.storage.DB.Save(&updatedBlock)
-	return updatedBlock, result.Error
+	result := buc.storage.DB.Preload("ExerciseBlocks").Preload("Exercises.Tags").First(&block, block.ID)
+	return block, result.Error
+}
+
+// Clone copies block visible to user with all its slots into a new draft block owned by user
+func (buc *BlocksUseCase) Clone(user *models.User, id int) (models.Block, error) {
+	var clone models.Block
+	err := buc.storage.DB.Transaction(func(tx *gorm.DB) error {
+		var block models.Block
+		result := tx.Scopes(visibleTo(user, KindBlock)).Preload("ExerciseBlocks").First(&block, id)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		var err error
+		clone, err = cloneBlock(tx, &block, user.ID)
+		return err
+	})
+	if err != nil {
+		return clone, err
+	}
+
+	result := buc.storage.DB.Preload("ExerciseBlocks").Preload("Exercises.Tags").First(&clone, clone.ID)
+	return clone, result.Error
+}
+
+func (buc *BlocksUseCase) Timeline(user *models.User, id int) (models.Block, []Segment, error) {
+	block, err := buc.Find(user, id)
+	if err != nil {
+		return block, nil, err
+	}
+	return block, BlockTimeline(&block), nil
+}
+
+func (buc *BlocksUseCase) Validate(user *models.User, id int) ([]Issue, error) {
+	var block models.Block
+	result := buc.storage.DB.Scopes(visibleTo(user, KindBlock)).Preload("ExerciseBlocks").First(&block, id)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return buc.validations.Block(&block)
 }
 
-func (buc *BlocksUseCase) ToggleDraft(id int) (models.Block, error) {
+func (buc *BlocksUseCase) ToggleDraft(user *models.User, id int) (models.Block, error) {
 	var block models.Block
-	result := buc.storage.DB.First(&block, id)
+	result := buc.storage.DB.Scopes(visibleTo(user, KindBlock)).Preload("ExerciseBlocks").First(&block, id)
 	if result.Error != nil {
 		return block, result.Error
 	}
+	if err := checkOwner(user, block.OwnerID); err != nil {
+		return block, err
+	}
+
+	// publishing is refused while block has any error level issue
+	if block.Draft {
+		issues, err := buc.validations.Block(&block)
+		if err != nil {
+			return block, err
+		}
+		if HasErrors(issues) {
+			return block, &NotReadyError{Err: ErrBlockNotReady, Issues: issues}
+		}
+	}
+
+	// published version can be unpublished only while no training relies on it
+	if !block.Draft {
+		if err := checkBlockNotPinned(buc.storage.DB, block.ID); err != nil {
+			return block, err
+		}
+	}
 
 	result = buc.storage.DB.Model(&block).Update("draft", !block.Draft)
 	if result.Error != nil {
 		return block, result.Error
 	}
 
-	result = buc.storage.DB.Preload("ExerciseBlocks").Preload("Exercises").First(&block, id)
+	result = buc.storage.DB.Preload("ExerciseBlocks").Preload("Exercises.Tags").First(&block, id)
 	return block, result.Error
 }
 
-func (buc *BlocksUseCase) Delete(id int) error {
+func (buc *BlocksUseCase) Delete(user *models.User, id int) error {
+	var block *models.Block
+	result := buc.storage.DB.Scopes(visibleTo(user, KindBlock)).First(&block, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if err := checkOwner(user, block.OwnerID); err != nil {
+		return err
+	}
+
 	//check if block has related workout
 	var trainingBlocks []models.TrainingBlock
-	result := buc.storage.DB.Where("block_id = ?", id).Find(&trainingBlocks)
+	result = buc.storage.DB.Where("block_id = ?", id).Find(&trainingBlocks)
 	if result.Error != nil {
 		return result.Error
 	}
@@ -274,12 +687,6 @@ func (buc *BlocksUseCase) Delete(id int) error {
 			return result.Error
 		}
 	}
-	var block *models.Block
-	result = buc.storage.DB.First(&block, id)
-	if result.Error != nil {
-		return result.Error
-	}
-
 	result = buc.storage.DB.Delete(&models.Block{}, id)
 	return result.Error
 }
@@ -287,3 +694,137 @@ func (buc *BlocksUseCase) Delete(id int) error {
 func (buc *BlocksUseCase) checkBlockFullOfExercises(block *models.Block) bool {
 	return int(block.TotalDuration)*60 == len(block.ExerciseBlocks)*int(block.OnTime+block.RelaxTime)
 }
+
+func oppositeSide(side string) string {
+	if side == "left" {
+		return "right"
+	}
+	return "left"
+}
+
+// findPairIndex returns index of pair slot for slot at index or -1 if it is not paired
+func findPairIndex(ebs []models.ExerciseBlock, index int) int {
+	if ebs[index].PairSlotID == nil {
+		return -1
+	}
+	pairID := *ebs[index].PairSlotID
+	return slices.IndexFunc(ebs, func(eb models.ExerciseBlock) bool {
+		return eb.ID == pairID
+	})
+}
+
+// splitPair takes out slot at index with its pair slot keeping their relative order
+func splitPair(ebs []models.ExerciseBlock, index int) ([]models.ExerciseBlock, []models.ExerciseBlock) {
+	pairIndex := findPairIndex(ebs, index)
+
+	var taken, rest []models.ExerciseBlock
+	for i, eb := range ebs {
+		if i == index || i == pairIndex {
+			taken = append(taken, eb)
+		} else {
+			rest = append(rest, eb)
+		}
+	}
+	return taken, rest
+}
+
+// keepPairsTogether puts every pair slot right after the first slot of its pair
+func keepPairsTogether(ebs []models.ExerciseBlock) []models.ExerciseBlock {
+	placed := make(map[uint]bool, len(ebs))
+	arr := make([]models.ExerciseBlock, 0, len(ebs))
+	for i, eb := range ebs {
+		if placed[eb.ID] {
+			continue
+		}
+		arr = append(arr, eb)
+		placed[eb.ID] = true
+
+		if pairIndex := findPairIndex(ebs, i); pairIndex != -1 && !placed[ebs[pairIndex].ID] {
+			arr = append(arr, ebs[pairIndex])
+			placed[ebs[pairIndex].ID] = true
+		}
+	}
+	return arr
+}
+
+// cloneBlock creates private draft copy of block with preloaded ExerciseBlocks, copy starts its own lineage of owner
+func cloneBlock(tx *gorm.DB, block *models.Block, ownerID uint) (models.Block, error) {
+	titleEn, titleRu, err := copyBlockTitles(tx, block)
+	if err != nil {
+		return models.Block{}, err
+	}
+
+	clone, err := copyBlock(tx, block, models.Block{
+		TitleEn:       titleEn,
+		TitleRu:       titleRu,
+		TotalDuration: block.TotalDuration,
+		OnTime:        block.OnTime,
+		RelaxTime:     block.RelaxTime,
+		Draft:         true,
+		Version:       1,
+		OwnerID:       &ownerID,
+		Visibility:    VisibilityPrivate,
+	})
+	if err != nil {
+		return clone, err
+	}
+
+	clone.LineageID = clone.ID
+	result := tx.Model(&clone).Update("lineage_id", clone.LineageID)
+	return clone, result.Error
+}
+
+// copyBlockTitles finds free titles like "Title (copy 2)"
+func copyBlockTitles(tx *gorm.DB, block *models.Block) (string, string, error) {
+	return freeBlockTitles(tx, func(n int) (string, string) {
+		if n == 1 {
+			return fmt.Sprintf("%s (copy)", block.TitleEn), fmt.Sprintf("%s (копия)", block.TitleRu)
+		}
+		return fmt.Sprintf("%s (copy %d)", block.TitleEn, n), fmt.Sprintf("%s (копия %d)", block.TitleRu, n)
+	})
+}
+
+// freeBlockTitles tries titles made for n = 1, 2, ... until both are free,
+// block titles are unique even among deleted ones
+func freeBlockTitles(tx *gorm.DB, titles func(n int) (string, string)) (string, string, error) {
+	for n := 1; ; n++ {
+		titleEn, titleRu := titles(n)
+
+		var count int64
+		result := tx.Unscoped().Model(&models.Block{}).Where("title_en = ? OR title_ru = ?", titleEn, titleRu).Count(&count)
+		if result.Error != nil {
+			return "", "", result.Error
+		}
+		if count == 0 {
+			return titleEn, titleRu, nil
+		}
+	}
+}
+
+// reorder returns items placed in order of given positions,
+// order should be a permutation of all items positions
+func reorder[T any](items []T, order []uint) ([]T, error) {
+	if len(order) != len(items) {
+		return nil, ErrInvalidOrder
+	}
+
+	seen := make([]bool, len(items))
+	reordered := make([]T, len(items))
+	for i, position := range order {
+		if int(position) >= len(items) || seen[position] {
+			return nil, ErrInvalidOrder
+		}
+		seen[position] = true
+		reordered[i] = items[position]
+	}
+	return reordered, nil
+}
+
+// blockCapacity is count of exercises which fit in block total duration
+func blockCapacity(block *models.Block) int {
+	period := int(block.OnTime) + int(block.RelaxTime)
+	if period == 0 {
+		return 0
+	}
+	return int(block.TotalDuration) * 60 / period
+}
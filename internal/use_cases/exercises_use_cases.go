@@ -8,7 +8,23 @@ import (
 	"fmt"
 	"path/filepath"
 	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// MuscleGroups are the only values allowed in Exercise.MuscleGroups
+var MuscleGroups = []string{"chest", "back", "shoulders", "arms", "core", "glutes", "legs", "full_body", "cardio"}
+
+const MaxDifficulty = 3
+
+var (
+	ErrInvalidMuscleGroup = fmt.Errorf("muscle group is invalid\nchoose one of %s", strings.Join(MuscleGroups, ", "))
+	ErrInvalidDifficulty  = fmt.Errorf("difficulty should be from 1 to %d", MaxDifficulty)
+	ErrTagNotFound        = errors.New("tag not found")
 )
 
 type ExercisesUseCase struct {
@@ -19,26 +35,48 @@ func NewExercisesUseCase(st *storage.Storage) *ExercisesUseCase {
 	return &ExercisesUseCase{storage: st}
 }
 
-func (euc *ExercisesUseCase) List(req *requests.FilterExercisesRequestBody) ([]*models.Exercise, error) {
+// List returns exercises visible to user
+func (euc *ExercisesUseCase) List(user *models.User, req *requests.FilterExercisesRequestBody) ([]*models.Exercise, error) {
 	var exercises []*models.Exercise
+	query := euc.storage.DB.Scopes(visibleTo(user, KindExercise))
 
 	if len(req.BlockIDs) != 0 {
-		result := euc.storage.DB.Joins("INNER JOIN exercise_blocks ON exercise_blocks.exercise_id = exercises.id").
-			Where("exercise_blocks.block_id IN ?", req.BlockIDs).Find(&exercises)
+		result := query.Joins("INNER JOIN exercise_blocks ON exercise_blocks.exercise_id = exercises.id").
+			Where("exercise_blocks.block_id IN ?", req.BlockIDs).Preload("Tags").Find(&exercises)
 		return exercises, result.Error
 	}
 
 	if req.Suggestion != "" {
-		result := euc.storage.DB.Where("title_en ILIKE ? OR title_ru ILIKE ?", "%"+req.Suggestion+"%", "%"+req.Suggestion+"%").Find(&exercises)
+		result := query.Where("title_en ILIKE ? OR title_ru ILIKE ?", "%"+req.Suggestion+"%", "%"+req.Suggestion+"%").Preload("Tags").Find(&exercises)
 		return exercises, result.Error
 	}
 
-	result := euc.storage.DB.Order(fmt.Sprintf("updated_at %s", req.UpdatedAt)).Find(&exercises)
+	result := query.Order(fmt.Sprintf("updated_at %s", req.UpdatedAt)).Preload("Tags").Find(&exercises)
 	return exercises, result.Error
 }
 
-func (euc *ExercisesUseCase) Create(req *requests.CreateExerciseRequest) (*models.Exercise, error) {
+// Create makes private exercise owned by user
+func (euc *ExercisesUseCase) Create(user *models.User, req *requests.CreateExerciseRequest) (*models.Exercise, error) {
 	e := req.Exercise
+	e.OwnerID = &user.ID
+	e.Visibility = VisibilityPrivate
+	if err := checkMuscleGroups(e.MuscleGroups); err != nil {
+		return nil, err
+	}
+	// zero difficulty is omitted on create, so database default is used
+	if e.Difficulty > MaxDifficulty {
+		return nil, ErrInvalidDifficulty
+	}
+	e.Equipment = normalizeEquipment(e.Equipment)
+	tagIDs, err := parseTagIDs(req.TagIds)
+	if err != nil {
+		return nil, err
+	}
+	e.Tags, err = euc.findTags(tagIDs)
+	if err != nil {
+		return nil, err
+	}
+
 	// todo if filename is already used, try another one. Maxtries = 5
 	path, err := euc.storage.S3.Upload(euc.makeFilename(e.TitleEn, req.FileHeader.Filename), *req.File, req.FileHeader.Header.Get("Content-Type"))
 	if err != nil {
@@ -49,15 +87,21 @@ func (euc *ExercisesUseCase) Create(req *requests.CreateExerciseRequest) (*model
 	return e, result.Error
 }
 
-func (euc *ExercisesUseCase) Find(id int) (*models.Exercise, error) {
+func (euc *ExercisesUseCase) Find(user *models.User, id int) (*models.Exercise, error) {
 	var e models.Exercise
-	result := euc.storage.DB.First(&e, id)
+	result := euc.storage.DB.Scopes(visibleTo(user, KindExercise)).Preload("Tags").First(&e, id)
 	return &e, result.Error
 }
 
-func (euc *ExercisesUseCase) Update(id int, req *requests.UpdateExerciseRequestBody) (*models.Exercise, error) {
+func (euc *ExercisesUseCase) Update(user *models.User, id int, req *requests.UpdateExerciseRequestBody) (*models.Exercise, error) {
 	var e *models.Exercise
-	result := euc.storage.DB.First(&e, id)
+	result := euc.storage.DB.Scopes(visibleTo(user, KindExercise)).First(&e, id)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if err := checkOwner(user, e.OwnerID); err != nil {
+		return nil, err
+	}
 	if req.TitleRu != "" {
 		e.TitleRu = req.TitleRu
 	}
@@ -67,9 +111,98 @@ func (euc *ExercisesUseCase) Update(id int, req *requests.UpdateExerciseRequestB
 	if len(req.Tips) != 0 {
 		e.Tips = req.Tips
 	}
+	if req.Unilateral != nil {
+		e.Unilateral = *req.Unilateral
+	}
+	if req.MuscleGroups != nil {
+		if err := checkMuscleGroups(req.MuscleGroups); err != nil {
+			return nil, err
+		}
+		e.MuscleGroups = req.MuscleGroups
+	}
+	if req.Equipment != nil {
+		e.Equipment = normalizeEquipment(req.Equipment)
+	}
+	if req.Difficulty != nil {
+		if *req.Difficulty == 0 || *req.Difficulty > MaxDifficulty {
+			return nil, ErrInvalidDifficulty
+		}
+		e.Difficulty = *req.Difficulty
+	}
 
-	result = euc.storage.DB.Save(e)
-	return e, result.Error
+	err := euc.storage.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Omit(clause.Associations).Save(e)
+		if result.Error != nil {
+			return result.Error
+		}
+		if req.TagIDs == nil {
+			return nil
+		}
+		tags, err := euc.findTags(req.TagIDs)
+		if err != nil {
+			return err
+		}
+		return tx.Model(e).Association("Tags").Replace(tags)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return euc.Find(user, int(e.ID))
+}
+
+func (euc *ExercisesUseCase) findTags(ids []uint) ([]models.Tag, error) {
+	tags := make([]models.Tag, 0)
+	if len(ids) == 0 {
+		return tags, nil
+	}
+	result := euc.storage.DB.Where("id IN ?", ids).Find(&tags)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	for _, id := range ids {
+		if !slices.ContainsFunc(tags, func(t models.Tag) bool { return t.ID == id }) {
+			return nil, fmt.Errorf("%w: id=%d", ErrTagNotFound, id)
+		}
+	}
+	return tags, nil
+}
+
+func checkMuscleGroups(groups []string) error {
+	for _, g := range groups {
+		if !slices.Contains(MuscleGroups, g) {
+			return fmt.Errorf("%w: %q", ErrInvalidMuscleGroup, g)
+		}
+	}
+	return nil
+}
+
+// normalizeEquipment makes equipment names comparable, "Resistance Band " is the same as "resistance band"
+func normalizeEquipment(equipment []string) []string {
+	normalized := make([]string, 0, len(equipment))
+	for _, item := range equipment {
+		item = strings.ToLower(strings.TrimSpace(item))
+		if item != "" && !slices.Contains(normalized, item) {
+			normalized = append(normalized, item)
+		}
+	}
+	return normalized
+}
+
+// parseTagIDs parses form value `1,2,3`
+func parseTagIDs(value string) ([]uint, error) {
+	var ids []uint
+	for _, s := range strings.Split(value, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("tag id %q is invalid: %w", s, err)
+		}
+		ids = append(ids, uint(id))
+	}
+	return ids, nil
 }
 
 func (euc *ExercisesUseCase) makeFilename(title, filename string) string {
@@ -99,10 +232,19 @@ func (euc *ExercisesUseCase) sanitizeFilename(filename string) string {
 	return sanitized
 }
 
-func (euc *ExercisesUseCase) Delete(id int) error {
+func (euc *ExercisesUseCase) Delete(user *models.User, id int) error {
+	var e *models.Exercise
+	result := euc.storage.DB.Scopes(visibleTo(user, KindExercise)).First(&e, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if err := checkOwner(user, e.OwnerID); err != nil {
+		return err
+	}
+
 	//check if exercise has related block
 	var exerciseBlocks []models.ExerciseBlock
-	result := euc.storage.DB.Where("exercise_id = ?", id).Find(&exerciseBlocks)
+	result = euc.storage.DB.Where("exercise_id = ?", id).Find(&exerciseBlocks)
 	if result.Error != nil {
 		return result.Error
 	}
@@ -126,12 +268,6 @@ func (euc *ExercisesUseCase) Delete(id int) error {
 		}
 	}
 
-	var e *models.Exercise
-	result = euc.storage.DB.First(&e, id)
-	if result.Error != nil {
-		return result.Error
-	}
-
 	spl := strings.Split(e.Filename, "/")
 	err := euc.storage.S3.Delete(spl[0])
 	if err != nil {
@@ -0,0 +1,469 @@
+package use_cases
+
+import (
+	"bf_me/internal/models"
+	"bf_me/internal/requests"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+)
+
+const (
+	MinGeneratedDuration = 10  // minutes, the shortest block
+	MaxGeneratedDuration = 180 // minutes
+
+	// generated blocks have one exercise per minute
+	generatedOnTime    = 40
+	generatedRelaxTime = 20
+)
+
+var (
+	ErrInvalidGeneratedDuration = fmt.Errorf("duration should be from %d to %d minutes", MinGeneratedDuration, MaxGeneratedDuration)
+	ErrNothingToGenerate        = errors.New("no published blocks or exercises match constraints\nloosen them")
+	ErrDurationNotReached       = errors.New("not enough published blocks and exercises match constraints to fill duration\nloosen them or shorten it")
+)
+
+// Generate builds a draft training of user of published blocks which match constraints,
+// the time left is filled with new draft blocks of matching exercises
+func (tuc *TrainingsUseCase) Generate(user *models.User, req *requests.GenerateTrainingRequestBody) (*models.Training, []models.Block, error) {
+	if req.Duration < MinGeneratedDuration || req.Duration > MaxGeneratedDuration {
+		return nil, []models.Block{}, ErrInvalidGeneratedDuration
+	}
+	if err := checkMuscleGroups(req.MuscleGroups); err != nil {
+		return nil, []models.Block{}, err
+	}
+
+	var training models.Training
+	err := tuc.storage.DB.Transaction(func(tx *gorm.DB) error {
+		exercises, err := generatorExercises(tx, user, req)
+		if err != nil {
+			return err
+		}
+		blocks, err := latestPublishedBlocks(tx, user)
+		if err != nil {
+			return err
+		}
+
+		g := newGenerator(req, exercises)
+		chosen := g.pickBlocks(blocks)
+
+		for minutes := g.freeMinutes(len(chosen)); minutes >= MinGeneratedDuration; minutes = g.freeMinutes(len(chosen)) {
+			block, err := g.buildBlock(tx, tuc.blocks, user, minutes, len(chosen))
+			if err != nil {
+				return err
+			}
+			if block == nil {
+				break
+			}
+			chosen = append(chosen, *block)
+		}
+		if len(chosen) == 0 {
+			return ErrNothingToGenerate
+		}
+		// less than the shortest block may stay free
+		if g.freeMinutes(len(chosen)) >= MinGeneratedDuration {
+			return ErrDurationNotReached
+		}
+
+		training, err = tuc.createGenerated(tx, user, req, chosen)
+		return err
+	})
+	if err != nil {
+		return nil, []models.Block{}, err
+	}
+
+	return tuc.Find(user, int(training.ID))
+}
+
+func (tuc *TrainingsUseCase) createGenerated(tx *gorm.DB, user *models.User, req *requests.GenerateTrainingRequestBody, blocks []models.Block) (models.Training, error) {
+	training := models.Training{
+		TitleEn:    req.TitleEn,
+		TitleRu:    req.TitleRu,
+		Draft:      true,
+		Version:    1,
+		OwnerID:    &user.ID,
+		Visibility: VisibilityPrivate,
+	}
+	if training.TitleEn == "" {
+		training.TitleEn = "Generated training"
+	}
+	if training.TitleRu == "" {
+		training.TitleRu = "Сгенерированная тренировка"
+	}
+	result := tx.Create(&training)
+	if result.Error != nil {
+		return training, result.Error
+	}
+
+	training.LineageID = training.ID
+	result = tx.Model(&training).Update("lineage_id", training.LineageID)
+	if result.Error != nil {
+		return training, result.Error
+	}
+
+	for i, block := range blocks {
+		tb := models.TrainingBlock{
+			TrainingID:     training.ID,
+			BlockID:        block.ID,
+			BlockOrder:     uint(i),
+			TransitionRest: DefaultBlockTransition,
+			Repeats:        1,
+		}
+		result = tx.Create(&tb)
+		if result.Error != nil {
+			return training, result.Error
+		}
+	}
+	return training, nil
+}
+
+// generatorExercises finds exercises visible to user and allowed by difficulty, equipment and exclusions ordered by id
+func generatorExercises(tx *gorm.DB, user *models.User, req *requests.GenerateTrainingRequestBody) ([]models.Exercise, error) {
+	query := tx.Scopes(visibleTo(user, KindExercise)).Preload("Tags").Order("id")
+	if req.Difficulty != 0 {
+		query = query.Where("difficulty <= ?", req.Difficulty)
+	}
+	if req.Equipment != nil {
+		query = query.Where("equipment <@ ?", pq.StringArray(normalizeEquipment(req.Equipment)))
+	}
+	if len(req.ExcludeExerciseIDs) != 0 {
+		query = query.Where("id NOT IN ?", req.ExcludeExerciseIDs)
+	}
+
+	var exercises []models.Exercise
+	result := query.Find(&exercises)
+	return exercises, result.Error
+}
+
+// latestPublishedBlocks returns the latest published version of every lineage visible to user ordered by id
+func latestPublishedBlocks(tx *gorm.DB, user *models.User) ([]models.Block, error) {
+	var blocks []models.Block
+	result := tx.Scopes(visibleTo(user, KindBlock)).Preload("ExerciseBlocks").Where("draft = ?", false).
+		Order("lineage_id, version DESC").Find(&blocks)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	latest := make([]models.Block, 0, len(blocks))
+	for _, b := range blocks {
+		if len(latest) != 0 && latest[len(latest)-1].LineageID == b.LineageID {
+			continue
+		}
+		latest = append(latest, b)
+	}
+	slices.SortFunc(latest, func(a, b models.Block) int {
+		return int(a.ID) - int(b.ID)
+	})
+	return latest, nil
+}
+
+type generator struct {
+	req       *requests.GenerateTrainingRequestBody
+	exercises []models.Exercise
+	allowed   map[uint]*models.Exercise
+	groups    []string        // muscle groups to balance
+	used      map[uint]bool   // exercises already in training
+	coverage  map[string]uint // work seconds of every muscle group
+	elapsed   uint            // seconds
+}
+
+func newGenerator(req *requests.GenerateTrainingRequestBody, exercises []models.Exercise) *generator {
+	g := &generator{
+		req:       req,
+		exercises: exercises,
+		allowed:   make(map[uint]*models.Exercise, len(exercises)),
+		used:      make(map[uint]bool),
+		coverage:  make(map[string]uint),
+	}
+	for i := range exercises {
+		g.allowed[exercises[i].ID] = &exercises[i]
+	}
+
+	// without focus every muscle group which can be trained is balanced
+	g.groups = slices.Clone(req.MuscleGroups)
+	if len(g.groups) == 0 {
+		for _, e := range exercises {
+			for _, group := range e.MuscleGroups {
+				if !slices.Contains(g.groups, group) {
+					g.groups = append(g.groups, group)
+				}
+			}
+		}
+	}
+	return g
+}
+
+// pickBlocks greedily takes the block with the best focus share and muscle balance while it fits target duration
+func (g *generator) pickBlocks(blocks []models.Block) []models.Block {
+	chosen := make([]models.Block, 0)
+	taken := make(map[uint]bool)
+	for {
+		best := -1
+		var bestScore float64
+		for i := range blocks {
+			if taken[blocks[i].ID] || !g.fits(&blocks[i], len(chosen)) {
+				continue
+			}
+			focus := g.blockFocus(&blocks[i])
+			if focus == 0 {
+				continue
+			}
+			score := focus - g.imbalance(g.blockCoverage(&blocks[i]))
+			if best == -1 || score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+		if best == -1 {
+			return chosen
+		}
+
+		block := blocks[best]
+		taken[block.ID] = true
+		g.take(&block, len(chosen))
+		chosen = append(chosen, block)
+	}
+}
+
+// fits checks that block is full, has only allowed exercises, repeats none of them
+// and does not exceed target duration together with transition before it
+func (g *generator) fits(block *models.Block, position int) bool {
+	capacity := blockCapacity(block)
+	if capacity == 0 || len(block.ExerciseBlocks) != capacity {
+		return false
+	}
+
+	seen := make(map[uint]bool)
+	for i, eb := range block.ExerciseBlocks {
+		if g.allowed[eb.ExerciseID] == nil || g.used[eb.ExerciseID] {
+			return false
+		}
+		// the second slot of unilateral pair is not a repeat
+		if seen[eb.ExerciseID] && findPairIndex(block.ExerciseBlocks, i) == -1 {
+			return false
+		}
+		seen[eb.ExerciseID] = true
+	}
+
+	return g.elapsed+g.transition(position)+g.blockDuration(block) <= uint(g.req.Duration)*60
+}
+
+func (g *generator) take(block *models.Block, position int) {
+	for group, seconds := range g.blockCoverage(block) {
+		g.coverage[group] = seconds
+	}
+	for _, eb := range block.ExerciseBlocks {
+		g.used[eb.ExerciseID] = true
+	}
+	g.elapsed += g.transition(position) + g.blockDuration(block)
+}
+
+func (g *generator) transition(position int) uint {
+	if position == 0 {
+		return 0
+	}
+	return DefaultBlockTransition
+}
+
+// blockDuration uses the same timing rules as timeline, exercises are not needed for it
+func (g *generator) blockDuration(block *models.Block) uint {
+	return TimelineDuration(BlockTimeline(block))
+}
+
+// freeMinutes is time left for one more block after blocks count of blocks
+func (g *generator) freeMinutes(blocks int) uint16 {
+	target := uint(g.req.Duration) * 60
+	spent := g.elapsed + g.transition(blocks)
+	if spent >= target {
+		return 0
+	}
+	return uint16(min((target-spent)/60, 60))
+}
+
+// blockFocus is a share of work time spent on focused exercises, it is 1 without focus
+func (g *generator) blockFocus(block *models.Block) float64 {
+	if len(g.req.TagIDs) == 0 && len(g.req.MuscleGroups) == 0 {
+		return 1
+	}
+	if len(block.ExerciseBlocks) == 0 {
+		return 0
+	}
+
+	focused := 0
+	for _, eb := range block.ExerciseBlocks {
+		if g.focused(g.allowed[eb.ExerciseID]) {
+			focused++
+		}
+	}
+	return float64(focused) / float64(len(block.ExerciseBlocks))
+}
+
+func (g *generator) focused(exercise *models.Exercise) bool {
+	if exercise == nil {
+		return false
+	}
+	if len(g.req.TagIDs) == 0 && len(g.req.MuscleGroups) == 0 {
+		return true
+	}
+	for _, tag := range exercise.Tags {
+		if slices.Contains(g.req.TagIDs, tag.ID) {
+			return true
+		}
+	}
+	for _, group := range exercise.MuscleGroups {
+		if slices.Contains(g.req.MuscleGroups, group) {
+			return true
+		}
+	}
+	return false
+}
+
+// blockCoverage is muscle groups coverage after adding block
+func (g *generator) blockCoverage(block *models.Block) map[string]uint {
+	coverage := make(map[string]uint, len(g.coverage))
+	for group, seconds := range g.coverage {
+		coverage[group] = seconds
+	}
+	for _, eb := range block.ExerciseBlocks {
+		if exercise := g.allowed[eb.ExerciseID]; exercise != nil {
+			for _, group := range exercise.MuscleGroups {
+				coverage[group] += uint(block.OnTime)
+			}
+		}
+	}
+	return coverage
+}
+
+// imbalance is from 0 when balanced groups got the same work time to 1 when only one of them got it
+func (g *generator) imbalance(coverage map[string]uint) float64 {
+	if len(g.groups) == 0 {
+		return 0
+	}
+
+	var sum, highest uint
+	lowest := coverage[g.groups[0]]
+	for _, group := range g.groups {
+		sum += coverage[group]
+		highest = max(highest, coverage[group])
+		lowest = min(lowest, coverage[group])
+	}
+	if sum == 0 {
+		return 0
+	}
+	return float64(highest-lowest) / float64(sum)
+}
+
+// buildBlock creates draft block of up to minutes length at position of training and fills it with unused exercises,
+// focused ones go first and every next one is the most balancing. Block is shortened to stay full,
+// it returns nil if unused exercises are not enough for the shortest block
+func (g *generator) buildBlock(tx *gorm.DB, buc *BlocksUseCase, user *models.User, minutes uint16, position int) (*models.Block, error) {
+	capacity := int(minutes) * 60 / (generatedOnTime + generatedRelaxTime)
+	coverage := make(map[string]uint, len(g.coverage))
+	for group, seconds := range g.coverage {
+		coverage[group] = seconds
+	}
+	picked := make([]*models.Exercise, 0)
+	slots := 0
+	for slots < capacity {
+		exercise := g.pickExercise(capacity - slots)
+		if exercise == nil {
+			break
+		}
+		picked = append(picked, exercise)
+		g.used[exercise.ID] = true
+		for _, group := range exercise.MuscleGroups {
+			g.coverage[group] += generatedOnTime * uint(exerciseSlots(exercise))
+		}
+		slots += exerciseSlots(exercise)
+	}
+
+	duration := uint8(slots * (generatedOnTime + generatedRelaxTime) / 60)
+	if duration < MinGeneratedDuration {
+		// exercises stay unused for nothing is built of them
+		for _, exercise := range picked {
+			delete(g.used, exercise.ID)
+		}
+		g.coverage = coverage
+		return nil, nil
+	}
+	titleEn, titleRu, err := freeBlockTitles(tx, func(n int) (string, string) {
+		if n == 1 {
+			return "Generated block", "Сгенерированный блок"
+		}
+		return fmt.Sprintf("Generated block %d", n), fmt.Sprintf("Сгенерированный блок %d", n)
+	})
+	if err != nil {
+		return nil, err
+	}
+	block := models.Block{
+		TitleEn:       titleEn,
+		TitleRu:       titleRu,
+		TotalDuration: duration,
+		OnTime:        generatedOnTime,
+		RelaxTime:     generatedRelaxTime,
+		Draft:         true,
+		Version:       1,
+		OwnerID:       &user.ID,
+		Visibility:    VisibilityPrivate,
+	}
+	result := tx.Create(&block)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	block.LineageID = block.ID
+	result = tx.Model(&block).Update("lineage_id", block.LineageID)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	var order uint = 0
+	for _, exercise := range picked {
+		created, err := buc.createSlots(tx, exercise, block.ID, order, "")
+		if err != nil {
+			return nil, err
+		}
+		block.ExerciseBlocks = append(block.ExerciseBlocks, created...)
+		order += uint(len(created))
+	}
+	g.elapsed += g.transition(position) + g.blockDuration(&block)
+	return &block, nil
+}
+
+// pickExercise returns unused exercise which fits into free slots,
+// focused exercises are preferred and then the one which makes muscle groups the most balanced
+func (g *generator) pickExercise(free int) *models.Exercise {
+	var best *models.Exercise
+	var bestScore float64
+	for i := range g.exercises {
+		exercise := &g.exercises[i]
+		if g.used[exercise.ID] || exerciseSlots(exercise) > free {
+			continue
+		}
+
+		coverage := make(map[string]uint, len(g.coverage))
+		for group, seconds := range g.coverage {
+			coverage[group] = seconds
+		}
+		for _, group := range exercise.MuscleGroups {
+			coverage[group] += generatedOnTime * uint(exerciseSlots(exercise))
+		}
+		score := -g.imbalance(coverage)
+		if g.focused(exercise) {
+			score += 2
+		}
+		if best == nil || score > bestScore {
+			best, bestScore = exercise, score
+		}
+	}
+	return best
+}
+
+// exerciseSlots is 2 for unilateral exercise which takes left and right slots
+func exerciseSlots(exercise *models.Exercise) int {
+	if exercise.Unilateral {
+		return 2
+	}
+	return 1
+}
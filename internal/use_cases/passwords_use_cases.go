@@ -0,0 +1,153 @@
+package use_cases
+
+import (
+	"bf_me/internal/models"
+	"bf_me/internal/requests"
+	"bf_me/internal/services"
+	"bf_me/internal/storage"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const PasswordResetLifetime = time.Hour
+
+var (
+	ErrWrongPassword     = errors.New("current password is wrong")
+	ErrInvalidResetToken = errors.New("reset token is invalid, used or expired\nrequest a new one")
+)
+
+type PasswordsUseCase struct {
+	storage  *storage.Storage
+	mailer   services.Mailer
+	resetURL string
+}
+
+// NewPasswordsUseCase makes use case which mails reset tokens with mailer, resetURL is the page
+// of reset which gets token as query parameter, without it the bare token is mailed
+func NewPasswordsUseCase(st *storage.Storage, mailer services.Mailer, resetURL string) *PasswordsUseCase {
+	return &PasswordsUseCase{storage: st, mailer: mailer, resetURL: resetURL}
+}
+
+// Change sets new password of user and ends all other sessions of user, the current one stays.
+// currentSessionID is nil for requests without session
+func (puc *PasswordsUseCase) Change(user *models.User, currentSessionID *pgtype.UUID, req *requests.ChangePasswordRequestBody) error {
+	return puc.storage.DB.Transaction(func(tx *gorm.DB) error {
+		var u models.User
+		result := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&u, user.ID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if services.WrongPassword(req.CurrentPassword, u.PasswordHash) {
+			return ErrWrongPassword
+		}
+		if err := setPassword(tx, &u, req.NewPassword); err != nil {
+			return err
+		}
+
+		query := tx.Where("user_id = ?", u.ID)
+		if currentSessionID != nil {
+			query = query.Where("id <> ?", *currentSessionID)
+		}
+		return query.Delete(&models.Session{}).Error
+	})
+}
+
+// RequestReset mails single-use token to user with login in background. Nothing tells whether such user exists,
+// neither result nor time of request, so failures are only logged
+func (puc *PasswordsUseCase) RequestReset(req *requests.RequestPasswordResetRequestBody) {
+	login := req.Login
+	go func() {
+		if err := puc.sendReset(login); err != nil {
+			log.Printf("failed to reset password by request: %s", err)
+		}
+	}()
+}
+
+// sendReset makes token of user with login and mails it, users without email or unknown logins are skipped
+func (puc *PasswordsUseCase) sendReset(login string) error {
+	var u models.User
+	result := puc.storage.DB.Where("login = ? AND disabled = false AND email IS NOT NULL", login).First(&u)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if result.Error != nil {
+		return result.Error
+	}
+
+	token, err := services.NewToken()
+	if err != nil {
+		return err
+	}
+	reset := models.PasswordReset{
+		UserID:    u.ID,
+		TokenHash: services.HashToken(token),
+		ExpiresAt: time.Now().Add(PasswordResetLifetime),
+	}
+	result = puc.storage.DB.Create(&reset)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	link := token
+	if puc.resetURL != "" {
+		link = puc.resetURL + "?token=" + url.QueryEscape(token)
+	}
+	body := fmt.Sprintf("Someone asked to reset the password of %s.\n\n"+
+		"Use this to set a new one within %s:\n%s\n\nIgnore this mail if it was not you.", u.Login, PasswordResetLifetime, link)
+	if err = puc.mailer.Send(*u.Email, "Password reset", body); err != nil {
+		// the token is useless when it was not delivered
+		log.Printf("failed to mail password reset of user %d: %s", u.ID, err)
+		return puc.storage.DB.Delete(&reset).Error
+	}
+	return nil
+}
+
+// Reset sets new password by mailed token. The token and all other tokens of user are used up,
+// and all sessions of user are ended
+func (puc *PasswordsUseCase) Reset(req *requests.ResetPasswordRequestBody) error {
+	return puc.storage.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		var reset models.PasswordReset
+		result := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("User").
+			Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", services.HashToken(req.Token), now).
+			First(&reset)
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return ErrInvalidResetToken
+		}
+		if result.Error != nil {
+			return result.Error
+		}
+		if reset.User.Disabled {
+			return ErrUserDisabled
+		}
+
+		if err := setPassword(tx, &reset.User, req.NewPassword); err != nil {
+			return err
+		}
+		result = tx.Model(&models.PasswordReset{}).Where("user_id = ? AND used_at IS NULL", reset.UserID).
+			Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		return tx.Where("user_id = ?", reset.UserID).Delete(&models.Session{}).Error
+	})
+}
+
+// setPassword checks new password by policy and stores its hash
+func setPassword(tx *gorm.DB, user *models.User, password string) error {
+	if err := services.CheckPassword(password, user.Login); err != nil {
+		return err
+	}
+	hash, err := services.HashPassword(password)
+	if err != nil {
+		return fmt.Errorf("password error: %s", err)
+	}
+	return tx.Model(user).Update("password_hash", hash).Error
+}
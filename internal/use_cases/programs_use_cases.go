@@ -0,0 +1,279 @@
+package use_cases
+
+import (
+	"bf_me/internal/models"
+	"bf_me/internal/requests"
+	"bf_me/internal/storage"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	MaxProgramWeeks = 52
+	DaysInWeek      = 7
+)
+
+var (
+	ErrProgramNotReady   = errors.New("program is not ready to be published\nfix all issues")
+	ErrProgramPublished  = errors.New("program is published\nunpublish it to edit")
+	ErrInvalidWeeks      = fmt.Errorf("program should have from 1 to %d weeks", MaxProgramWeeks)
+	ErrDayOutOfRange     = errors.New("week or day is out of program range")
+	ErrTrainingScheduled = errors.New("training is a part of published program\nunpublish the program first")
+)
+
+type ProgramsUseCase struct {
+	storage     *storage.Storage
+	validations *ValidationsUseCase
+}
+
+func NewProgramsUseCase(st *storage.Storage) *ProgramsUseCase {
+	return &ProgramsUseCase{storage: st, validations: NewValidationsUseCase(st)}
+}
+
+func (puc *ProgramsUseCase) List(user *models.User, req *requests.FilterRequestBody) ([]*models.Program, error) {
+	var programs []*models.Program
+	query := puc.storage.DB.Scopes(visibleTo(user, KindProgram)).Order(fmt.Sprintf("updated_at %s", req.UpdatedAt)).Preload("Days")
+
+	if req.Suggestion != "" {
+		query = query.Where("title_en ILIKE ? OR title_ru ILIKE ?", "%"+req.Suggestion+"%", "%"+req.Suggestion+"%")
+	}
+	if req.BlockType == "draft" {
+		query = query.Where("draft = ?", true)
+	}
+	if req.BlockType == "ready" {
+		query = query.Where("draft = ?", false)
+	}
+
+	result := query.Find(&programs)
+	return programs, result.Error
+}
+
+func (puc *ProgramsUseCase) Create(user *models.User, req *requests.ProgramRequestBody) (*models.Program, []models.Training, error) {
+	program := models.Program{
+		TitleEn:    req.TitleEn,
+		TitleRu:    req.TitleRu,
+		Draft:      true,
+		Weeks:      req.Weeks,
+		OwnerID:    &user.ID,
+		Visibility: VisibilityPrivate,
+	}
+	if program.Weeks == 0 {
+		program.Weeks = 1
+	}
+	if program.Weeks > MaxProgramWeeks {
+		return nil, []models.Training{}, ErrInvalidWeeks
+	}
+
+	result := puc.storage.DB.Create(&program)
+	if result.Error != nil {
+		return nil, []models.Training{}, result.Error
+	}
+	return puc.Find(user, int(program.ID))
+}
+
+// Find returns program with its days and trainings of the days
+func (puc *ProgramsUseCase) Find(user *models.User, id int) (*models.Program, []models.Training, error) {
+	var program models.Program
+	result := puc.storage.DB.Scopes(visibleTo(user, KindProgram)).Preload("Days").First(&program, id)
+	if result.Error != nil {
+		return nil, []models.Training{}, result.Error
+	}
+
+	trainingIDs := make([]uint, 0, len(program.Days))
+	for _, d := range program.Days {
+		if d.TrainingID != nil {
+			trainingIDs = append(trainingIDs, *d.TrainingID)
+		}
+	}
+
+	var trainings []models.Training
+	result = puc.storage.DB.Where("id IN ?", trainingIDs).Find(&trainings)
+	return &program, trainings, result.Error
+}
+
+// Update changes titles and weeks count of draft program, days of removed weeks are removed
+func (puc *ProgramsUseCase) Update(user *models.User, id int, req *requests.ProgramRequestBody) (*models.Program, []models.Training, error) {
+	if req.Weeks > MaxProgramWeeks {
+		return nil, []models.Training{}, ErrInvalidWeeks
+	}
+
+	err := puc.storage.DB.Transaction(func(tx *gorm.DB) error {
+		program, err := puc.lockEditable(tx, user, uint(id))
+		if err != nil {
+			return err
+		}
+
+		if req.TitleEn != "" {
+			program.TitleEn = req.TitleEn
+		}
+		if req.TitleRu != "" {
+			program.TitleRu = req.TitleRu
+		}
+		if req.Weeks != 0 {
+			program.Weeks = req.Weeks
+			result := tx.Where("program_id = ? AND week >= ?", program.ID, program.Weeks).Delete(&models.ProgramDay{})
+			if result.Error != nil {
+				return result.Error
+			}
+		}
+
+		return tx.Omit(clause.Associations).Save(&program).Error
+	})
+	if err != nil {
+		return nil, []models.Training{}, err
+	}
+	return puc.Find(user, id)
+}
+
+// SetDay puts training or rest into the day of draft program replacing previous one
+func (puc *ProgramsUseCase) SetDay(user *models.User, id int, req *requests.SetProgramDayRequestBody) (*models.Program, []models.Training, error) {
+	err := puc.storage.DB.Transaction(func(tx *gorm.DB) error {
+		program, err := puc.lockEditable(tx, user, uint(id))
+		if err != nil {
+			return err
+		}
+		if req.Week >= program.Weeks || req.Day >= DaysInWeek {
+			return ErrDayOutOfRange
+		}
+
+		if req.TrainingID != nil {
+			var training models.Training
+			result := tx.Scopes(visibleTo(user, KindTraining)).First(&training, *req.TrainingID)
+			if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+				return ErrTrainingDeleted
+			}
+			if result.Error != nil {
+				return result.Error
+			}
+		}
+
+		var day models.ProgramDay
+		result := tx.Where("program_id = ? AND week = ? AND day = ?", program.ID, req.Week, req.Day).Find(&day)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected != 0 {
+			return tx.Model(&day).Update("training_id", req.TrainingID).Error
+		}
+
+		day = models.ProgramDay{ProgramID: program.ID, Week: req.Week, Day: req.Day, TrainingID: req.TrainingID}
+		return tx.Create(&day).Error
+	})
+	if err != nil {
+		return nil, []models.Training{}, err
+	}
+	return puc.Find(user, id)
+}
+
+// RemoveDay clears the day slot of draft program
+func (puc *ProgramsUseCase) RemoveDay(user *models.User, id int, dayID uint) (*models.Program, []models.Training, error) {
+	err := puc.storage.DB.Transaction(func(tx *gorm.DB) error {
+		program, err := puc.lockEditable(tx, user, uint(id))
+		if err != nil {
+			return err
+		}
+
+		result := tx.Where("program_id = ?", program.ID).Delete(&models.ProgramDay{}, dayID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, []models.Training{}, err
+	}
+	return puc.Find(user, id)
+}
+
+// lockEditable locks draft program which user owns
+func (puc *ProgramsUseCase) lockEditable(tx *gorm.DB, user *models.User, id uint) (models.Program, error) {
+	var program models.Program
+	result := tx.Scopes(visibleTo(user, KindProgram)).Clauses(clause.Locking{Strength: "UPDATE"}).First(&program, id)
+	if result.Error != nil {
+		return program, result.Error
+	}
+	if err := checkOwner(user, program.OwnerID); err != nil {
+		return program, err
+	}
+	if !program.Draft {
+		return program, ErrProgramPublished
+	}
+	return program, nil
+}
+
+func (puc *ProgramsUseCase) Validate(user *models.User, id int) ([]Issue, error) {
+	var program models.Program
+	result := puc.storage.DB.Scopes(visibleTo(user, KindProgram)).Preload("Days").First(&program, id)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return puc.validations.Program(&program)
+}
+
+func (puc *ProgramsUseCase) ToggleDraft(user *models.User, id int) (*models.Program, []models.Training, error) {
+	var program models.Program
+	result := puc.storage.DB.Scopes(visibleTo(user, KindProgram)).Preload("Days").First(&program, id)
+	if result.Error != nil {
+		return nil, []models.Training{}, result.Error
+	}
+	if err := checkOwner(user, program.OwnerID); err != nil {
+		return nil, []models.Training{}, err
+	}
+
+	// publishing is refused while program has any error level issue
+	if program.Draft {
+		issues, err := puc.validations.Program(&program)
+		if err != nil {
+			return nil, []models.Training{}, err
+		}
+		if HasErrors(issues) {
+			return nil, []models.Training{}, &NotReadyError{Err: ErrProgramNotReady, Issues: issues}
+		}
+	}
+
+	result = puc.storage.DB.Model(&program).Update("draft", !program.Draft)
+	if result.Error != nil {
+		return nil, []models.Training{}, result.Error
+	}
+	return puc.Find(user, id)
+}
+
+func (puc *ProgramsUseCase) Delete(user *models.User, id int) error {
+	return puc.storage.DB.Transaction(func(tx *gorm.DB) error {
+		var program models.Program
+		result := tx.Scopes(visibleTo(user, KindProgram)).First(&program, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if err := checkOwner(user, program.OwnerID); err != nil {
+			return err
+		}
+
+		result = tx.Where("program_id = ?", program.ID).Delete(&models.ProgramDay{})
+		if result.Error != nil {
+			return result.Error
+		}
+		return tx.Delete(&program).Error
+	})
+}
+
+// checkTrainingNotScheduled is used before unpublishing and deleting training, published programs rely on it
+func checkTrainingNotScheduled(tx *gorm.DB, trainingID uint) error {
+	var count int64
+	result := tx.Model(&models.ProgramDay{}).
+		Joins("INNER JOIN programs ON programs.id = program_days.program_id AND programs.deleted_at IS NULL").
+		Where("program_days.training_id = ? AND programs.draft = ?", trainingID, false).Count(&count)
+	if result.Error != nil {
+		return result.Error
+	}
+	if count != 0 {
+		return ErrTrainingScheduled
+	}
+	return nil
+}
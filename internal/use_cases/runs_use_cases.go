@@ -0,0 +1,268 @@
+package use_cases
+
+import (
+	"bf_me/internal/models"
+	"bf_me/internal/requests"
+	"bf_me/internal/storage"
+	"errors"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	RunRunning  = "running"
+	RunPaused   = "paused"
+	RunFinished = "finished"
+)
+
+var (
+	ErrRunNotRunning = errors.New("run is not running")
+	ErrRunNotPaused  = errors.New("run is not paused")
+	ErrRunFinished   = errors.New("run is already finished")
+)
+
+// RunState is a snapshot of run at given moment, durations are in milliseconds.
+// SegmentIndex is -1 when run is finished
+type RunState struct {
+	Run           models.WorkoutRun
+	Status        string
+	Position      int64
+	Elapsed       int64
+	TotalDuration int64
+	SegmentIndex  int
+	Segment       *Segment
+	Remaining     int64 // till the end of current segment
+	Next          *Segment
+	segments      []Segment
+}
+
+type RunsUseCase struct {
+	storage *storage.Storage
+}
+
+func NewRunsUseCase(st *storage.Storage) *RunsUseCase {
+	return &RunsUseCase{storage: st}
+}
+
+// Start begins a run of published training which user can see right now
+func (ruc *RunsUseCase) Start(user *models.User, req *requests.StartRunRequestBody) (RunState, error) {
+	var training models.Training
+	result := ruc.storage.DB.Scopes(visibleTo(user, KindTraining)).First(&training, req.TrainingID)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return RunState{}, ErrTrainingDeleted
+	}
+	if result.Error != nil {
+		return RunState{}, result.Error
+	}
+	if training.Draft {
+		return RunState{}, ErrTrainingNotPublished
+	}
+
+	now := time.Now()
+	run := models.WorkoutRun{UserID: user.ID, TrainingID: training.ID, Status: RunRunning, ResumedAt: &now}
+	result = ruc.storage.DB.Create(&run)
+	if result.Error != nil {
+		return RunState{}, result.Error
+	}
+	return ruc.State(user.ID, int(run.ID))
+}
+
+// Find returns run of user and segments of its training which are loaded once for following the run
+func (ruc *RunsUseCase) Find(userID uint, id int) (*models.WorkoutRun, []Segment, error) {
+	var run models.WorkoutRun
+	result := ruc.storage.DB.Where("user_id = ?", userID).First(&run, id)
+	if result.Error != nil {
+		return nil, nil, result.Error
+	}
+
+	segments, err := trainingSegments(ruc.storage.DB, run.TrainingID)
+	return &run, segments, err
+}
+
+// Reload refreshes run changed by another screen
+func (ruc *RunsUseCase) Reload(run *models.WorkoutRun) error {
+	return ruc.storage.DB.First(run, run.ID).Error
+}
+
+func (ruc *RunsUseCase) State(userID uint, id int) (RunState, error) {
+	run, segments, err := ruc.Find(userID, id)
+	if err != nil {
+		return RunState{}, err
+	}
+	return RunStateAt(run, segments, time.Now()), nil
+}
+
+func (ruc *RunsUseCase) Pause(userID uint, id int) (RunState, error) {
+	return ruc.change(userID, id, func(run *models.WorkoutRun, state RunState, now time.Time) error {
+		if state.Status != RunRunning {
+			return ErrRunNotRunning
+		}
+		run.Status = RunPaused
+		run.PositionMs = state.Position
+		run.ElapsedMs = state.Elapsed
+		run.ResumedAt = nil
+		return nil
+	})
+}
+
+func (ruc *RunsUseCase) Resume(userID uint, id int) (RunState, error) {
+	return ruc.change(userID, id, func(run *models.WorkoutRun, state RunState, now time.Time) error {
+		if state.Status != RunPaused {
+			return ErrRunNotPaused
+		}
+		run.Status = RunRunning
+		run.ResumedAt = &now
+		return nil
+	})
+}
+
+// Skip jumps to the next exercise, the rest after skipped one is skipped too
+func (ruc *RunsUseCase) Skip(userID uint, id int) (RunState, error) {
+	return ruc.change(userID, id, func(run *models.WorkoutRun, state RunState, now time.Time) error {
+		if state.Status == RunFinished {
+			return ErrRunFinished
+		}
+		segments := state.segments
+
+		if state.Segment.Kind == SegmentWork {
+			run.SkippedSegments = append(run.SkippedSegments, int64(state.SegmentIndex))
+		}
+		run.PositionMs = state.TotalDuration
+		for i := state.SegmentIndex + 1; i < len(segments); i++ {
+			if segments[i].Kind == SegmentWork {
+				run.PositionMs = int64(segments[i].Start) * 1000
+				break
+			}
+		}
+		run.ElapsedMs = state.Elapsed
+		if run.Status == RunRunning {
+			run.ResumedAt = &now
+		}
+		if run.PositionMs >= state.TotalDuration {
+			finishRun(run, now)
+		}
+		return nil
+	})
+}
+
+func (ruc *RunsUseCase) Finish(userID uint, id int) (RunState, error) {
+	return ruc.change(userID, id, func(run *models.WorkoutRun, state RunState, now time.Time) error {
+		// run which reached the end by time can still be finished explicitly
+		if state.Run.Status == RunFinished {
+			return ErrRunFinished
+		}
+		run.PositionMs = state.Position
+		run.ElapsedMs = state.Elapsed
+		finishRun(run, now)
+		return nil
+	})
+}
+
+// change locks run, applies update to it with its current state and saves it
+func (ruc *RunsUseCase) change(userID uint, id int, update func(run *models.WorkoutRun, state RunState, now time.Time) error) (RunState, error) {
+	var state RunState
+	err := ruc.storage.DB.Transaction(func(tx *gorm.DB) error {
+		var run models.WorkoutRun
+		result := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("user_id = ?", userID).First(&run, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		segments, err := trainingSegments(tx, run.TrainingID)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		current := RunStateAt(&run, segments, now)
+		// run which reached the end by time is finished before any change
+		if current.Status == RunFinished && run.Status != RunFinished {
+			run.PositionMs = current.Position
+			run.ElapsedMs = current.Elapsed
+			finishRun(&run, now)
+		}
+		if err = update(&run, current, now); err != nil {
+			return err
+		}
+
+		result = tx.Save(&run)
+		if result.Error != nil {
+			return result.Error
+		}
+		state = RunStateAt(&run, segments, now)
+		return nil
+	})
+	return state, err
+}
+
+// trainingSegments builds timeline of exact training version, it is the same as the one shown by timeline endpoint
+func trainingSegments(tx *gorm.DB, trainingID uint) ([]Segment, error) {
+	var training models.Training
+	result := tx.Unscoped().Preload("TrainingBlocks").First(&training, trainingID)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	blockIDs := make([]uint, len(training.TrainingBlocks))
+	for i, tb := range training.TrainingBlocks {
+		blockIDs[i] = tb.BlockID
+	}
+	var blocks []models.Block
+	result = tx.Unscoped().Preload("ExerciseBlocks").Preload("Exercises").Where("id IN ?", blockIDs).Find(&blocks)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return TrainingTimeline(&training, blocks), nil
+}
+
+func finishRun(run *models.WorkoutRun, now time.Time) {
+	run.Status = RunFinished
+	run.ResumedAt = nil
+	run.FinishedAt = &now
+}
+
+// RunStateAt computes state of run at now, running run reaching the end of timeline is finished
+func RunStateAt(run *models.WorkoutRun, segments []Segment, now time.Time) RunState {
+	state := RunState{
+		Run:           *run,
+		Status:        run.Status,
+		Position:      run.PositionMs,
+		Elapsed:       run.ElapsedMs,
+		TotalDuration: int64(TimelineDuration(segments)) * 1000,
+		SegmentIndex:  -1,
+		segments:      segments,
+	}
+	if run.Status == RunRunning && run.ResumedAt != nil {
+		since := now.Sub(*run.ResumedAt).Milliseconds()
+		state.Position += since
+		state.Elapsed += since
+	}
+	if state.Position >= state.TotalDuration {
+		// time after the end is not active
+		state.Elapsed -= state.Position - state.TotalDuration
+		state.Position = state.TotalDuration
+		state.Status = RunFinished
+	}
+	if state.Status == RunFinished {
+		return state
+	}
+
+	index, _ := slices.BinarySearchFunc(segments, state.Position, func(s Segment, position int64) int {
+		end := int64(s.Start+s.Duration) * 1000
+		if end <= position {
+			return -1
+		}
+		return 1
+	})
+	if index < len(segments) {
+		state.SegmentIndex = index
+		state.Segment = &segments[index]
+		state.Remaining = int64(segments[index].Start+segments[index].Duration)*1000 - state.Position
+	}
+	if index+1 < len(segments) {
+		state.Next = &segments[index+1]
+	}
+	return state
+}
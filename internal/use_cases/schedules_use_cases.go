@@ -0,0 +1,299 @@
+package use_cases
+
+import (
+	"bf_me/internal/models"
+	"bf_me/internal/requests"
+	"bf_me/internal/services"
+	"bf_me/internal/storage"
+	"errors"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const MaxScheduleRange = 366 * 24 * time.Hour
+
+var (
+	ErrTrainingNotPublished = errors.New("training is draft\npublish it before scheduling")
+	ErrInvalidTimezone      = errors.New("timezone is invalid\nuse IANA name like Europe/Moscow")
+	ErrInvalidStart         = errors.New("date should be like 2006-01-02 and time like 15:04")
+	ErrInvalidRange         = errors.New("range should end after its start and be up to a year long")
+	ErrStartNotInRule       = errors.New("start date should be one of BYDAY days of recurrence rule")
+)
+
+// Occurrence is one session of a possibly recurring scheduled session
+type Occurrence struct {
+	Session  models.ScheduledSession
+	StartsAt time.Time
+	EndsAt   time.Time
+}
+
+type SchedulesUseCase struct {
+	storage *storage.Storage
+}
+
+func NewSchedulesUseCase(st *storage.Storage) *SchedulesUseCase {
+	return &SchedulesUseCase{storage: st}
+}
+
+func (suc *SchedulesUseCase) Create(user *models.User, req *requests.ScheduleRequestBody) (*models.ScheduledSession, error) {
+	if req.Date == "" || req.Time == "" {
+		return nil, ErrInvalidStart
+	}
+	s := models.ScheduledSession{UserID: user.ID, Timezone: "UTC"}
+	if err := suc.apply(user, &s, req); err != nil {
+		return nil, err
+	}
+
+	result := suc.storage.DB.Create(&s)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return suc.Find(user.ID, int(s.ID))
+}
+
+// Find returns scheduled session only if it belongs to user
+func (suc *SchedulesUseCase) Find(userID uint, id int) (*models.ScheduledSession, error) {
+	var s models.ScheduledSession
+	result := suc.storage.DB.Preload("Training").Where("user_id = ?", userID).First(&s, id)
+	return &s, result.Error
+}
+
+// Update changes given fields, empty ones keep current values
+func (suc *SchedulesUseCase) Update(user *models.User, id int, req *requests.ScheduleRequestBody) (*models.ScheduledSession, error) {
+	s, err := suc.Find(user.ID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err = suc.apply(user, s, req); err != nil {
+		return nil, err
+	}
+
+	result := suc.storage.DB.Omit("Training").Save(s)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return suc.Find(user.ID, id)
+}
+
+// apply checks request and copies it into scheduled session, date and time are local to timezone.
+// Only published training which user can see is scheduled
+func (suc *SchedulesUseCase) apply(user *models.User, s *models.ScheduledSession, req *requests.ScheduleRequestBody) error {
+	if req.TrainingID != 0 {
+		var training models.Training
+		result := suc.storage.DB.Scopes(visibleTo(user, KindTraining)).First(&training, req.TrainingID)
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return ErrTrainingDeleted
+		}
+		if result.Error != nil {
+			return result.Error
+		}
+		if training.Draft {
+			return ErrTrainingNotPublished
+		}
+		s.TrainingID = training.ID
+	}
+	if s.TrainingID == 0 {
+		return ErrTrainingDeleted
+	}
+
+	if req.Timezone != "" {
+		if _, err := time.LoadLocation(req.Timezone); err != nil {
+			return ErrInvalidTimezone
+		}
+	}
+
+	// previous local date and time stay the same when only timezone is changed
+	loc, _ := time.LoadLocation(s.Timezone)
+	if loc == nil {
+		loc = time.UTC
+	}
+	date := s.StartsAt.In(loc).Format(time.DateOnly)
+	clock := s.StartsAt.In(loc).Format("15:04")
+	if req.Date != "" {
+		date = req.Date
+	}
+	if req.Time != "" {
+		clock = req.Time
+	}
+	if req.Timezone != "" {
+		s.Timezone = req.Timezone
+		loc, _ = time.LoadLocation(s.Timezone)
+	}
+	startsAt, err := time.ParseInLocation("2006-01-02 15:04", date+" "+clock, loc)
+	if err != nil {
+		return ErrInvalidStart
+	}
+	s.StartsAt = startsAt.UTC()
+
+	if req.RRule != nil {
+		rule, err := services.ParseRRule(*req.RRule)
+		if err != nil {
+			return err
+		}
+		s.RRule = ""
+		if rule != nil {
+			s.RRule = rule.String()
+		}
+	}
+	// either start or rule may be changed, so the stored rule is checked
+	if rule, _ := services.ParseRRule(s.RRule); rule != nil && !rule.StartsOn(startsAt) {
+		return ErrStartNotInRule
+	}
+	if req.Notes != nil {
+		s.Notes = *req.Notes
+	}
+	return nil
+}
+
+func (suc *SchedulesUseCase) Delete(userID uint, id int) error {
+	result := suc.storage.DB.Where("user_id = ?", userID).Delete(&models.ScheduledSession{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// Range expands scheduled sessions of user into occurrences starting in [from, to) ordered by start
+func (suc *SchedulesUseCase) Range(userID uint, req *requests.ScheduleRangeRequestBody) ([]Occurrence, error) {
+	from, err := time.Parse(time.RFC3339, req.From)
+	if err != nil {
+		return nil, ErrInvalidRange
+	}
+	to, err := time.Parse(time.RFC3339, req.To)
+	if err != nil || !to.After(from) || to.Sub(from) > MaxScheduleRange {
+		return nil, ErrInvalidRange
+	}
+
+	var sessions []models.ScheduledSession
+	result := suc.storage.DB.Preload("Training").
+		Where("user_id = ? AND starts_at < ? AND (rrule <> '' OR starts_at >= ?)", userID, to, from).
+		Find(&sessions)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	trainingIDs := make([]uint, len(sessions))
+	for i, s := range sessions {
+		trainingIDs[i] = s.TrainingID
+	}
+	durations, err := trainingDurations(suc.storage.DB, trainingIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	occurrences := make([]Occurrence, 0)
+	for _, s := range sessions {
+		starts, err := sessionStarts(&s, from, to)
+		if err != nil {
+			return nil, err
+		}
+		duration := time.Duration(durations[s.TrainingID]) * time.Second
+		for _, start := range starts {
+			occurrences = append(occurrences, Occurrence{Session: s, StartsAt: start, EndsAt: start.Add(duration)})
+		}
+	}
+
+	slices.SortStableFunc(occurrences, func(a, b Occurrence) int {
+		return a.StartsAt.Compare(b.StartsAt)
+	})
+	return occurrences, nil
+}
+
+// sessionStarts returns starts of scheduled session in [from, to) in its timezone
+func sessionStarts(s *models.ScheduledSession, from, to time.Time) ([]time.Time, error) {
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	start := s.StartsAt.In(loc)
+
+	rule, err := services.ParseRRule(s.RRule)
+	if err != nil {
+		return nil, err
+	}
+	if rule == nil {
+		if start.Before(from) || !start.Before(to) {
+			return nil, nil
+		}
+		return []time.Time{start}, nil
+	}
+	return rule.Between(start, from, to), nil
+}
+
+// IssueFeedToken replaces secret token of user iCalendar feed, the previous feed url stops working
+func (suc *SchedulesUseCase) IssueFeedToken(userID uint) (string, error) {
+	token, err := services.NewToken()
+	if err != nil {
+		return "", err
+	}
+
+	hash := services.HashToken(token)
+	result := suc.storage.DB.Model(&models.User{}).Where("id = ?", userID).Update("calendar_token_hash", hash)
+	if result.Error != nil {
+		return "", result.Error
+	}
+	if result.RowsAffected == 0 {
+		return "", gorm.ErrRecordNotFound
+	}
+	return token, nil
+}
+
+// Feed returns all scheduled sessions of user with given feed token and durations of their trainings in seconds
+func (suc *SchedulesUseCase) Feed(token string) ([]models.ScheduledSession, map[uint]uint, error) {
+	var user models.User
+	result := suc.storage.DB.Where("calendar_token_hash = ?", services.HashToken(token)).First(&user)
+	if result.Error != nil {
+		return nil, nil, result.Error
+	}
+
+	var sessions []models.ScheduledSession
+	result = suc.storage.DB.Preload("Training").Where("user_id = ?", user.ID).Order("starts_at").Find(&sessions)
+	if result.Error != nil {
+		return nil, nil, result.Error
+	}
+
+	trainingIDs := make([]uint, len(sessions))
+	for i, s := range sessions {
+		trainingIDs[i] = s.TrainingID
+	}
+	durations, err := trainingDurations(suc.storage.DB, trainingIDs)
+	return sessions, durations, err
+}
+
+// trainingDurations computes timeline duration in seconds of every training
+func trainingDurations(db *gorm.DB, trainingIDs []uint) (map[uint]uint, error) {
+	durations := make(map[uint]uint, len(trainingIDs))
+	if len(trainingIDs) == 0 {
+		return durations, nil
+	}
+
+	var trainings []models.Training
+	result := db.Unscoped().Preload("TrainingBlocks").Where("id IN ?", trainingIDs).Find(&trainings)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	var blockIDs []uint
+	for _, t := range trainings {
+		for _, tb := range t.TrainingBlocks {
+			blockIDs = append(blockIDs, tb.BlockID)
+		}
+	}
+	var blocks []models.Block
+	if len(blockIDs) != 0 {
+		result = db.Unscoped().Preload("ExerciseBlocks").Where("id IN ?", blockIDs).Find(&blocks)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+	}
+
+	for i := range trainings {
+		durations[trainings[i].ID] = TimelineDuration(TrainingTimeline(&trainings[i], blocks))
+	}
+	return durations, nil
+}
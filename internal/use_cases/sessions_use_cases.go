@@ -5,12 +5,33 @@ import (
 	"bf_me/internal/requests"
 	"bf_me/internal/services"
 	"bf_me/internal/storage"
+	"context"
 	"errors"
 	"fmt"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"gorm.io/gorm"
+)
+
+const (
+	SessionLifetime      = 90 * 24 * time.Hour // since login
+	SessionIdleTimeout   = 14 * 24 * time.Hour // since the last use
+	sessionTouchInterval = time.Minute
+	MaxDeviceIDLength    = 64
 )
 
 var (
-	ErrNoMoreUser = errors.New("no more users can register")
+	ErrUserDisabled    = errors.New("user is disabled")
+	ErrInvalidDeviceID = fmt.Errorf("device id should be up to %d characters", MaxDeviceIDLength)
+)
+
+type contextKey string
+
+const (
+	sessionContextKey contextKey = "session"
+	userContextKey    contextKey = "user"
 )
 
 type SessionsUseCase struct {
@@ -21,37 +42,12 @@ func NewSessionsUseCase(st *storage.Storage) *SessionsUseCase {
 	return &SessionsUseCase{storage: st}
 }
 
-func (suc *SessionsUseCase) CreateUser(req requests.UserRequestBody) (*models.Session, error) {
-	// limiting registered users
-	var count int64
-	result := suc.storage.DB.Table("users").Count(&count)
-	if result.Error != nil {
-		return nil, result.Error
-	}
-
-	if count > 1 {
-		return nil, ErrNoMoreUser
-	}
-
-	var u models.User
-	var err error
-	u.Login = req.Login
-	u.PasswordHash, err = services.HashPassword(req.Password)
-	if err != nil {
-		return nil, fmt.Errorf("password error: %s", err)
-	}
-
-	result = suc.storage.DB.Create(&u)
-	if result.Error != nil {
-		return nil, fmt.Errorf("create user error: %s", result.Error)
+// Create logs user in on device, the previous session of the same device is replaced.
+// Devices are told apart by id which client keeps, without it every login is a new device
+func (suc *SessionsUseCase) Create(req *requests.UserRequestBody, userAgent, ip string) (*models.Session, error) {
+	if len(req.DeviceID) > MaxDeviceIDLength {
+		return nil, ErrInvalidDeviceID
 	}
-	var s models.Session
-	s.User = u
-	result = suc.storage.DB.Create(&s)
-	return &s, result.Error
-}
-
-func (suc *SessionsUseCase) Create(req *requests.UserRequestBody) (*models.Session, error) {
 	var u models.User
 	result := suc.storage.DB.Where("login = ?", req.Login).First(&u)
 	if result.Error != nil {
@@ -62,32 +58,132 @@ func (suc *SessionsUseCase) Create(req *requests.UserRequestBody) (*models.Sessi
 	if wrong {
 		return nil, fmt.Errorf("wrong password")
 	}
-	var sessions []models.Session
-	suc.storage.DB.Where("user_id = ?", u.ID).Delete(&sessions)
+	if u.Disabled {
+		return nil, ErrUserDisabled
+	}
 
-	var s models.Session
-	s.User = u
-	result = suc.storage.DB.Create(&s)
-	return &s, result.Error
+	var s *models.Session
+	err := suc.storage.DB.Transaction(func(tx *gorm.DB) error {
+		if req.DeviceID != "" {
+			result := tx.Where("user_id = ? AND device_id = ?", u.ID, req.DeviceID).Delete(&models.Session{})
+			if result.Error != nil {
+				return result.Error
+			}
+		}
+		var err error
+		s, err = createSession(tx, &u, req.DeviceID, userAgent, ip)
+		return err
+	})
+	return s, err
+}
+
+// List returns sessions of user which are not ended, the recently used first
+func (suc *SessionsUseCase) List(userID uint) ([]models.Session, error) {
+	var sessions []models.Session
+	result := suc.storage.DB.Scopes(activeSessions(time.Now())).Where("user_id = ?", userID).
+		Order("last_seen_at DESC").Find(&sessions)
+	return sessions, result.Error
 }
 
-func (suc *SessionsUseCase) Delete(sessionId string) error {
-	var session models.Session
-	result := suc.storage.DB.Where("id = ?", sessionId).First(&session)
+// Revoke ends one session of user by its id, sessions on other devices stay
+func (suc *SessionsUseCase) Revoke(userID uint, id pgtype.UUID) error {
+	result := suc.storage.DB.Where("user_id = ? AND id = ?", userID, id).Delete(&models.Session{})
 	if result.Error != nil {
 		return result.Error
 	}
-
-	var sessions []models.Session
-	result = suc.storage.DB.Where("user_id = ?", session.UserID).Delete(&sessions)
-	return result.Error
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
 }
 
-func (suc *SessionsUseCase) Find(sessionId string) (*models.Session, error) {
+// Find returns session with its user by token, ended sessions and sessions of disabled users are not valid.
+// Using session moves its idle expiry forward
+func (suc *SessionsUseCase) Find(token string) (*models.Session, error) {
+	now := time.Now()
 	var session *models.Session
-	result := suc.storage.DB.Where("id = ?", sessionId).First(&session)
+	result := suc.storage.DB.Scopes(activeSessions(now)).Preload("User").Where("token_hash = ?", services.HashToken(token)).First(&session)
 	if result.Error != nil {
 		return nil, result.Error
 	}
+	if session.User.Disabled {
+		return nil, ErrUserDisabled
+	}
+
+	// last seen time is written once in a while and not on every request
+	if now.Sub(session.LastSeenAt) > sessionTouchInterval {
+		session.LastSeenAt = now
+		result = suc.storage.DB.Model(session).Update("last_seen_at", now)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+	}
 	return session, nil
 }
+
+// Sweep deletes ended sessions and returns how many were deleted
+func (suc *SessionsUseCase) Sweep() (int64, error) {
+	now := time.Now()
+	result := suc.storage.DB.Where("expires_at <= ? OR last_seen_at <= ?", now, now.Add(-SessionIdleTimeout)).
+		Delete(&models.Session{})
+	return result.RowsAffected, result.Error
+}
+
+// StartSweeper runs Sweep every interval in background for the whole life of the app
+func (suc *SessionsUseCase) StartSweeper(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if _, err := suc.Sweep(); err != nil {
+				log.Printf("failed to sweep sessions: %s", err)
+			}
+		}
+	}()
+}
+
+func createSession(tx *gorm.DB, user *models.User, deviceID, userAgent, ip string) (*models.Session, error) {
+	token, err := services.NewToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	s := models.Session{
+		TokenHash:  services.HashToken(token),
+		User:       *user,
+		DeviceID:   deviceID,
+		UserAgent:  userAgent,
+		IP:         ip,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(SessionLifetime),
+	}
+	result := tx.Create(&s)
+	s.Token = token
+	return &s, result.Error
+}
+
+// activeSessions scopes sessions to the ones which are not ended at now
+func activeSessions(now time.Time) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("expires_at > ? AND last_seen_at > ?", now, now.Add(-SessionIdleTimeout))
+	}
+}
+
+// WithSession puts authenticated session and its user on context
+func WithSession(ctx context.Context, session *models.Session) context.Context {
+	ctx = context.WithValue(ctx, sessionContextKey, session)
+	return context.WithValue(ctx, userContextKey, &session.User)
+}
+
+// SessionFromContext returns session put by WithSession, it is nil for requests with API key
+func SessionFromContext(ctx context.Context) *models.Session {
+	session, _ := ctx.Value(sessionContextKey).(*models.Session)
+	return session
+}
+
+// UserFromContext returns user put by WithSession or WithAPIKey, it is nil for not authenticated requests
+func UserFromContext(ctx context.Context) *models.User {
+	user, _ := ctx.Value(userContextKey).(*models.User)
+	return user
+}
@@ -0,0 +1,106 @@
+package use_cases
+
+import (
+	"slices"
+)
+
+// Summary is computed from timeline segments, so it always follows the same timing rules as playback.
+// All durations are in seconds.
+type Summary struct {
+	TotalDuration  uint
+	WorkTime       uint
+	RestTime       uint // rest between exercises
+	TransitionTime uint // rest between rounds and blocks
+	Slots          uint // work segments
+	Exercises      uint // distinct exercises
+	LeftTime       uint // work of unilateral slots
+	RightTime      uint
+	Tags           []Share
+	MuscleGroups   []Share
+}
+
+// Share is work time of exercises with the tag or muscle group.
+// Exercise with several tags or groups counts for every one of them, so shares may add up to more than WorkTime
+type Share struct {
+	TagID    uint // zero for muscle groups
+	Name     string
+	WorkTime uint
+}
+
+// Balanced reports if left and right sides got the same work time
+func (s Summary) Balanced() bool {
+	return s.LeftTime == s.RightTime
+}
+
+func Summarize(segments []Segment) Summary {
+	summary := Summary{
+		TotalDuration: TimelineDuration(segments),
+		Tags:          make([]Share, 0),
+		MuscleGroups:  make([]Share, 0),
+	}
+	exercises := make(map[uint]bool)
+
+	for _, s := range segments {
+		switch s.Kind {
+		case SegmentRest:
+			summary.RestTime += s.Duration
+			continue
+		case SegmentTransition:
+			summary.TransitionTime += s.Duration
+			continue
+		}
+
+		summary.WorkTime += s.Duration
+		summary.Slots++
+		switch s.Side {
+		case "left":
+			summary.LeftTime += s.Duration
+		case "right":
+			summary.RightTime += s.Duration
+		}
+
+		if s.Exercise == nil {
+			continue
+		}
+		exercises[s.Exercise.ID] = true
+		for _, tag := range s.Exercise.Tags {
+			summary.Tags = addShare(summary.Tags, Share{TagID: tag.ID, Name: tag.TitleEn}, s.Duration)
+		}
+		for _, group := range s.Exercise.MuscleGroups {
+			summary.MuscleGroups = addShare(summary.MuscleGroups, Share{Name: group}, s.Duration)
+		}
+	}
+
+	summary.Exercises = uint(len(exercises))
+	sortShares(summary.Tags)
+	sortShares(summary.MuscleGroups)
+	return summary
+}
+
+func addShare(shares []Share, share Share, duration uint) []Share {
+	index := slices.IndexFunc(shares, func(s Share) bool {
+		return s.TagID == share.TagID && s.Name == share.Name
+	})
+	if index == -1 {
+		shares = append(shares, share)
+		index = len(shares) - 1
+	}
+	shares[index].WorkTime += duration
+	return shares
+}
+
+// sortShares puts the biggest share first, equal ones are ordered by name
+func sortShares(shares []Share) {
+	slices.SortStableFunc(shares, func(a, b Share) int {
+		if a.WorkTime != b.WorkTime {
+			return int(b.WorkTime) - int(a.WorkTime)
+		}
+		if a.Name < b.Name {
+			return -1
+		}
+		if a.Name > b.Name {
+			return 1
+		}
+		return 0
+	})
+}
@@ -0,0 +1,144 @@
+package use_cases
+
+import (
+	"bf_me/internal/models"
+	"slices"
+)
+
+const (
+	SegmentWork       = "work"
+	SegmentRest       = "rest"
+	SegmentTransition = "transition"
+
+	// DefaultBlockTransition is rest between two blocks of training, seconds
+	DefaultBlockTransition = 60
+)
+
+// Segment is a part of schedule, Start is offset in seconds from the beginning of block or training
+type Segment struct {
+	Kind     string
+	Start    uint
+	Duration uint
+	BlockID  uint
+	SlotID   uint
+	Side     string
+	Round    uint // round of block in training starting from 1
+	Exercise *models.Exercise
+}
+
+// BlockTimeline is the only place where schedule is computed,
+// every slot is OnTime of work followed by RelaxTime of rest.
+// Block should have preloaded ExerciseBlocks and Exercises
+func BlockTimeline(block *models.Block) []Segment {
+	return appendBlockSegments(make([]Segment, 0), block, 0)
+}
+
+// TrainingTimeline joins rounds of blocks in TrainingBlocks order,
+// every round is followed by TransitionRest of its training block unless it is the last one.
+// Rounds of a block which is in training several times go on counting, as workout results count them so
+// Training should have preloaded TrainingBlocks, blocks - ExerciseBlocks and Exercises
+func TrainingTimeline(training *models.Training, blocks []models.Block) []Segment {
+	segments := make([]Segment, 0)
+	var start uint = 0
+	var transition uint = 0
+	rounds := make(map[uint]uint) // of every block so far
+
+	for _, tb := range sortedTrainingBlocks(training.TrainingBlocks) {
+		index := slices.IndexFunc(blocks, func(b models.Block) bool {
+			return b.ID == tb.BlockID
+		})
+		if index == -1 {
+			continue
+		}
+
+		for range max(tb.Repeats, 1) {
+			rounds[tb.BlockID]++
+			round := rounds[tb.BlockID]
+			if len(segments) != 0 && transition != 0 {
+				segments = append(segments, Segment{
+					Kind:     SegmentTransition,
+					Start:    start,
+					Duration: transition,
+					BlockID:  tb.BlockID,
+					Round:    round,
+				})
+				start += transition
+			}
+
+			blockStart := len(segments)
+			segments = appendBlockSegments(segments, &blocks[index], start)
+			for i := blockStart; i < len(segments); i++ {
+				segments[i].Round = round
+			}
+			start = TimelineDuration(segments)
+			transition = uint(tb.TransitionRest)
+		}
+	}
+	return segments
+}
+
+// TimelineDuration is the end of the last segment in seconds
+func TimelineDuration(segments []Segment) uint {
+	if len(segments) == 0 {
+		return 0
+	}
+	last := segments[len(segments)-1]
+	return last.Start + last.Duration
+}
+
+func appendBlockSegments(segments []Segment, block *models.Block, start uint) []Segment {
+	for _, eb := range sortedExerciseBlocks(block.ExerciseBlocks) {
+		var exercise *models.Exercise
+		if index := slices.IndexFunc(block.Exercises, func(e models.Exercise) bool {
+			return e.ID == eb.ExerciseID
+		}); index != -1 {
+			exercise = &block.Exercises[index]
+		}
+
+		segments = append(segments, Segment{
+			Kind:     SegmentWork,
+			Start:    start,
+			Duration: uint(block.OnTime),
+			BlockID:  block.ID,
+			SlotID:   eb.ID,
+			Side:     eb.Side,
+			Exercise: exercise,
+		})
+		start += uint(block.OnTime)
+
+		if block.RelaxTime == 0 {
+			continue
+		}
+		segments = append(segments, Segment{
+			Kind:     SegmentRest,
+			Start:    start,
+			Duration: uint(block.RelaxTime),
+			BlockID:  block.ID,
+			SlotID:   eb.ID,
+		})
+		start += uint(block.RelaxTime)
+	}
+	return segments
+}
+
+func sortedExerciseBlocks(ebs []models.ExerciseBlock) []models.ExerciseBlock {
+	sorted := slices.Clone(ebs)
+	slices.SortStableFunc(sorted, func(a, b models.ExerciseBlock) int {
+		if a.ExerciseOrder != b.ExerciseOrder {
+			return int(a.ExerciseOrder) - int(b.ExerciseOrder)
+		}
+		return int(a.ID) - int(b.ID)
+	})
+	return sorted
+}
+
+func sortedTrainingBlocks(tbs []models.TrainingBlock) []models.TrainingBlock {
+	sorted := slices.Clone(tbs)
+	slices.SortStableFunc(sorted, func(a, b models.TrainingBlock) int {
+		if a.BlockOrder != b.BlockOrder {
+			return int(a.BlockOrder) - int(b.BlockOrder)
+		}
+		return int(a.ID) - int(b.ID)
+	})
+	return sorted
+}
@@ -6,124 +6,373 @@ import (
 	"bf_me/internal/storage"
 	"errors"
 	"fmt"
+	"slices"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 var (
-	ErrTrainingDeleted = errors.New("exercise was deleted\nchoose another one")
+	ErrTrainingDeleted       = errors.New("exercise was deleted\nchoose another one")
+	ErrTrainingNotReady      = errors.New("training is not ready to be published\nfix all issues")
+	ErrInvalidTransitionRest = fmt.Errorf("transition rest should be up to %d seconds", MaxBlockTransition)
+	ErrInvalidRepeats        = fmt.Errorf("block can be repeated from 1 to %d times", MaxBlockRepeats)
+)
+
+const (
+	MaxBlockTransition = 600 // seconds
+	MaxBlockRepeats    = 10
 )
 
 type TrainingsUseCase struct {
-	storage *storage.Storage
+	storage     *storage.Storage
+	validations *ValidationsUseCase
+	blocks      *BlocksUseCase
 }
 
 func NewTrainingsUseCase(st *storage.Storage) *TrainingsUseCase {
-	return &TrainingsUseCase{storage: st}
+	return &TrainingsUseCase{storage: st, validations: NewValidationsUseCase(st), blocks: NewBlocksUseCase(st)}
 }
 
-func (tuc *TrainingsUseCase) List(req *requests.FilterRequestBody) ([]*models.Training, error) {
+func (tuc *TrainingsUseCase) List(user *models.User, req *requests.FilterRequestBody) ([]*models.Training, error) {
 	var trainings []*models.Training
 	updatedAtSql := fmt.Sprintf("updated_at %s", req.UpdatedAt)
+	query := tuc.storage.DB.Scopes(visibleTo(user, KindTraining))
 
 	if req.Suggestion != "" {
-		result := tuc.storage.DB.Where("title_en ILIKE ? OR title_ru ILIKE ?", "%"+req.Suggestion+"%", "%"+req.Suggestion+"%").Find(&trainings)
+		result := query.Where("title_en ILIKE ? OR title_ru ILIKE ?", "%"+req.Suggestion+"%", "%"+req.Suggestion+"%").Find(&trainings)
 		return trainings, result.Error
 	}
 
 	if req.BlockType == "draft" {
-		result := tuc.storage.DB.Where("draft = ?", true).Order(updatedAtSql).Preload("TrainingBlocks").Find(&trainings)
+		result := query.Where("draft = ?", true).Order(updatedAtSql).Preload("TrainingBlocks").Find(&trainings)
 		return trainings, result.Error
 	}
 
 	if req.BlockType == "ready" {
-		result := tuc.storage.DB.Where("draft = ?", false).Order(updatedAtSql).Preload("TrainingBlocks").Find(&trainings)
+		result := query.Where("draft = ?", false).Order(updatedAtSql).Preload("TrainingBlocks").Find(&trainings)
 		return trainings, result.Error
 	}
 
-	result := tuc.storage.DB.Order(updatedAtSql).Preload("TrainingBlocks").Preload("Blocks").Find(&trainings)
+	result := query.Order(updatedAtSql).Preload("TrainingBlocks").Preload("Blocks").Find(&trainings)
 	return trainings, result.Error
 }
 
-func (tuc *TrainingsUseCase) AddTrainingBlock(trainingID, blockID uint) (*models.Training, []models.Block, error) {
-	var training models.Training
-	result := tuc.storage.DB.Preload("TrainingBlocks").First(&training, trainingID)
-	if result.Error != nil {
-		return nil, []models.Block{}, result.Error
+// AddTrainingBlock pins block version to the end of training,
+// published training is frozen so block is added to its new draft version
+func (tuc *TrainingsUseCase) AddTrainingBlock(user *models.User, trainingID, blockID uint) (*models.Training, []models.Block, error) {
+	return tuc.insertTrainingBlock(user, trainingID, blockID, nil, DefaultBlockTransition, 1)
+}
+
+func (tuc *TrainingsUseCase) InsertTrainingBlock(user *models.User, trainingID, blockID uint, req *requests.InsertTrainingBlockRequestBody) (*models.Training, []models.Block, error) {
+	transitionRest := uint16(DefaultBlockTransition)
+	if req.TransitionRest != nil {
+		transitionRest = *req.TransitionRest
 	}
-	if !training.Draft {
-		return nil, []models.Block{}, errors.New("block is not draft\ncannot add exercise")
+	repeats := uint8(1)
+	if req.Repeats != 0 {
+		repeats = req.Repeats
 	}
+	return tuc.insertTrainingBlock(user, trainingID, blockID, &req.Position, transitionRest, repeats)
+}
+
+// insertTrainingBlock puts block at given position and shifts following ones,
+// nil position appends block to the end of training
+func (tuc *TrainingsUseCase) insertTrainingBlock(user *models.User, trainingID, blockID uint, position *uint, transitionRest uint16, repeats uint8) (*models.Training, []models.Block, error) {
+	var training models.Training
+	err := tuc.storage.DB.Transaction(func(tx *gorm.DB) error {
+		if err := checkTrainingBlockSettings(transitionRest, repeats); err != nil {
+			return err
+		}
+
+		tbs, err := tuc.lockTrainingBlocks(tx, user, &training, trainingID)
+		if err != nil {
+			return err
+		}
+		if position != nil && int(*position) > len(tbs) {
+			return ErrSlotPositionOutOfRange
+		}
+
+		var block models.Block
+		result := tx.Scopes(visibleTo(user, KindBlock)).First(&block, blockID)
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return ErrTrainingDeleted
+		}
+		if result.Error != nil {
+			return result.Error
+		}
+
+		tbs, err = tuc.editableTrainingBlocks(tx, &training, tbs)
+		if err != nil {
+			return err
+		}
+
+		tb := models.TrainingBlock{
+			TrainingID:     training.ID,
+			BlockID:        blockID,
+			BlockOrder:     tuc.findNextOrder(tbs),
+			TransitionRest: transitionRest,
+			Repeats:        repeats,
+		}
+		result = tx.Create(&tb)
+		if result.Error != nil || position == nil {
+			return result.Error
+		}
 
-	var block models.Block
-	result = tuc.storage.DB.First(&block, blockID)
-	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-		return nil, []models.Block{}, ErrTrainingDeleted
+		return tuc.renumberTrainingBlocks(tx, slices.Insert(tbs, int(*position), tb))
+	})
+	if err != nil {
+		return nil, []models.Block{}, err
 	}
-	if result.Error != nil {
-		return nil, []models.Block{}, result.Error
+
+	return tuc.Find(user, int(training.ID))
+}
+
+// RemoveTrainingBlock removes the first slot with given block, use RemoveTrainingSlot to be precise
+func (tuc *TrainingsUseCase) RemoveTrainingBlock(user *models.User, trainingID, blockID uint) (*models.Training, []models.Block, error) {
+	return tuc.removeTrainingSlot(user, trainingID, func(tb models.TrainingBlock) bool {
+		return tb.BlockID == blockID
+	})
+}
+
+func (tuc *TrainingsUseCase) RemoveTrainingSlot(user *models.User, trainingID, slotID uint) (*models.Training, []models.Block, error) {
+	return tuc.removeTrainingSlot(user, trainingID, func(tb models.TrainingBlock) bool {
+		return tb.ID == slotID
+	})
+}
+
+func (tuc *TrainingsUseCase) removeTrainingSlot(user *models.User, trainingID uint, match func(tb models.TrainingBlock) bool) (*models.Training, []models.Block, error) {
+	var training models.Training
+	err := tuc.storage.DB.Transaction(func(tx *gorm.DB) error {
+		tbs, err := tuc.lockTrainingBlocks(tx, user, &training, trainingID)
+		if err != nil {
+			return err
+		}
+
+		index := slices.IndexFunc(tbs, match)
+		if index == -1 {
+			return gorm.ErrRecordNotFound
+		}
+		tbs, err = tuc.editableTrainingBlocks(tx, &training, tbs)
+		if err != nil {
+			return err
+		}
+
+		result := tx.Unscoped().Delete(&tbs[index])
+		if result.Error != nil {
+			return result.Error
+		}
+		return tuc.renumberTrainingBlocks(tx, slices.Delete(tbs, index, index+1))
+	})
+	if err != nil {
+		return nil, []models.Block{}, err
 	}
 
-	var tbs []models.TrainingBlock
-	result = tuc.storage.DB.Where("training_id = ?", trainingID).Find(&tbs)
-	if result.Error != nil {
-		return nil, []models.Block{}, result.Error
+	return tuc.Find(user, int(training.ID))
+}
+
+// UpdateTrainingSlot changes transition rest after block and count of block repeats
+func (tuc *TrainingsUseCase) UpdateTrainingSlot(user *models.User, trainingID, slotID uint, req *requests.UpdateTrainingSlotRequestBody) (*models.Training, []models.Block, error) {
+	var training models.Training
+	err := tuc.storage.DB.Transaction(func(tx *gorm.DB) error {
+		tbs, err := tuc.lockTrainingBlocks(tx, user, &training, trainingID)
+		if err != nil {
+			return err
+		}
+
+		index := slices.IndexFunc(tbs, func(tb models.TrainingBlock) bool {
+			return tb.ID == slotID
+		})
+		if index == -1 {
+			return gorm.ErrRecordNotFound
+		}
+		tbs, err = tuc.editableTrainingBlocks(tx, &training, tbs)
+		if err != nil {
+			return err
+		}
+
+		tb := tbs[index]
+		if req.TransitionRest != nil {
+			tb.TransitionRest = *req.TransitionRest
+		}
+		if req.Repeats != nil {
+			tb.Repeats = *req.Repeats
+		}
+		if err = checkTrainingBlockSettings(tb.TransitionRest, tb.Repeats); err != nil {
+			return err
+		}
+
+		result := tx.Model(&models.TrainingBlock{}).Where("id = ?", tb.ID).
+			Updates(map[string]interface{}{"transition_rest": tb.TransitionRest, "repeats": tb.Repeats})
+		return result.Error
+	})
+	if err != nil {
+		return nil, []models.Block{}, err
 	}
 
-	nextOrder := tuc.findNextOrder(training.TrainingBlocks)
-	eb := models.TrainingBlock{
-		TrainingID: trainingID,
-		BlockID:    blockID,
-		BlockOrder: nextOrder,
+	return tuc.Find(user, int(training.ID))
+}
+
+func (tuc *TrainingsUseCase) MoveTrainingBlock(user *models.User, trainingID uint, req *requests.MoveSlotRequestBody) (*models.Training, []models.Block, error) {
+	var training models.Training
+	err := tuc.storage.DB.Transaction(func(tx *gorm.DB) error {
+		tbs, err := tuc.lockTrainingBlocks(tx, user, &training, trainingID)
+		if err != nil {
+			return err
+		}
+		if int(req.From) >= len(tbs) || int(req.To) >= len(tbs) {
+			return ErrSlotPositionOutOfRange
+		}
+		tbs, err = tuc.editableTrainingBlocks(tx, &training, tbs)
+		if err != nil {
+			return err
+		}
+
+		tb := tbs[req.From]
+		tbs = slices.Delete(tbs, int(req.From), int(req.From)+1)
+		return tuc.renumberTrainingBlocks(tx, slices.Insert(tbs, int(req.To), tb))
+	})
+	if err != nil {
+		return nil, []models.Block{}, err
 	}
-	result = tuc.storage.DB.Create(&eb)
-	if result.Error != nil {
-		return nil, []models.Block{}, result.Error
+
+	return tuc.Find(user, int(training.ID))
+}
+
+// ReorderTrainingBlocks sets the whole order at once,
+// req.Order lists current positions of blocks in the new order
+func (tuc *TrainingsUseCase) ReorderTrainingBlocks(user *models.User, trainingID uint, req *requests.ReorderSlotsRequestBody) (*models.Training, []models.Block, error) {
+	var training models.Training
+	err := tuc.storage.DB.Transaction(func(tx *gorm.DB) error {
+		tbs, err := tuc.lockTrainingBlocks(tx, user, &training, trainingID)
+		if err != nil {
+			return err
+		}
+		tbs, err = tuc.editableTrainingBlocks(tx, &training, tbs)
+		if err != nil {
+			return err
+		}
+
+		reordered, err := reorder(tbs, req.Order)
+		if err != nil {
+			return err
+		}
+		return tuc.renumberTrainingBlocks(tx, reordered)
+	})
+	if err != nil {
+		return nil, []models.Block{}, err
 	}
 
-	result = tuc.storage.DB.Preload("TrainingBlocks").Preload("Blocks").First(&training, trainingID)
+	return tuc.Find(user, int(training.ID))
+}
+
+// lockTrainingBlocks locks training row of user until the end of transaction
+// and returns its blocks relations sorted by order
+func (tuc *TrainingsUseCase) lockTrainingBlocks(tx *gorm.DB, user *models.User, training *models.Training, trainingID uint) ([]models.TrainingBlock, error) {
+	result := tx.Scopes(visibleTo(user, KindTraining)).Clauses(clause.Locking{Strength: "UPDATE"}).First(training, trainingID)
 	if result.Error != nil {
-		return nil, []models.Block{}, result.Error
+		return nil, result.Error
 	}
-
-	blockIds := make([]uint, len(training.TrainingBlocks))
-	for i, t := range training.TrainingBlocks {
-		blockIds[i] = t.BlockID
+	if err := checkOwner(user, training.OwnerID); err != nil {
+		return nil, err
 	}
 
-	var blocks []models.Block
-	result = tuc.storage.DB.Preload("ExerciseBlocks").Preload("Exercises").Where("id IN ?", blockIds).Find(&blocks)
+	var tbs []models.TrainingBlock
+	result = tx.Where("training_id = ?", trainingID).Order("block_order, id").Find(&tbs)
+	return tbs, result.Error
+}
 
-	return &training, blocks, result.Error
+// editableTrainingBlocks switches published training to its new draft version,
+// returned relations of the new version are in the same order as given ones
+func (tuc *TrainingsUseCase) editableTrainingBlocks(tx *gorm.DB, training *models.Training, tbs []models.TrainingBlock) ([]models.TrainingBlock, error) {
+	if training.Draft {
+		return tbs, nil
+	}
+
+	training.TrainingBlocks = tbs
+	draft, err := editableTraining(tx, training)
+	if err != nil {
+		return nil, err
+	}
+	*training = draft
+	return draft.TrainingBlocks, nil
 }
 
-func (tuc *TrainingsUseCase) RemoveTrainingBlock(trainingID, blockID uint) (*models.Training, []models.Block, error) {
-	var trainingBlockRelation models.TrainingBlock
-	result := tuc.storage.DB.First(&trainingBlockRelation, "training_id = ? AND block_id = ?", trainingID, blockID)
-	if result.Error != nil {
-		return nil, []models.Block{}, result.Error
+// renumberTrainingBlocks stores blocks order without gaps starting from 0
+func (tuc *TrainingsUseCase) renumberTrainingBlocks(tx *gorm.DB, tbs []models.TrainingBlock) error {
+	for i, tb := range tbs {
+		if tb.BlockOrder == uint(i) {
+			continue
+		}
+		result := tx.Model(&models.TrainingBlock{}).Where("id = ?", tb.ID).Update("block_order", uint(i))
+		if result.Error != nil {
+			return result.Error
+		}
 	}
+	return nil
+}
 
-	result = tuc.storage.DB.Unscoped().Delete(&trainingBlockRelation)
-	if result.Error != nil {
-		return nil, []models.Block{}, result.Error
+func checkTrainingBlockSettings(transitionRest uint16, repeats uint8) error {
+	if transitionRest > MaxBlockTransition {
+		return ErrInvalidTransitionRest
 	}
+	if repeats < 1 || repeats > MaxBlockRepeats {
+		return ErrInvalidRepeats
+	}
+	return nil
+}
 
+// Upgrades shows which pinned block versions have newer published versions and what was changed
+func (tuc *TrainingsUseCase) Upgrades(user *models.User, id int) ([]BlockUpgrade, error) {
 	var training models.Training
-	result = tuc.storage.DB.Preload("TrainingBlocks").Preload("Blocks").First(&training, trainingID)
+	result := tuc.storage.DB.Scopes(visibleTo(user, KindTraining)).Preload("TrainingBlocks").First(&training, id)
 	if result.Error != nil {
-		return nil, []models.Block{}, result.Error
+		return nil, result.Error
 	}
+	return blockUpgrades(tuc.storage.DB, &training)
+}
 
-	blockIds := make([]uint, len(training.TrainingBlocks))
-	for i, t := range training.TrainingBlocks {
-		blockIds[i] = t.BlockID
-	}
+// Upgrade pins the latest published versions of all blocks,
+// published training is frozen so blocks are upgraded in its new draft version
+func (tuc *TrainingsUseCase) Upgrade(user *models.User, id int) (*models.Training, []models.Block, []BlockUpgrade, error) {
+	var training models.Training
+	var upgrades []BlockUpgrade
+	err := tuc.storage.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Scopes(visibleTo(user, KindTraining)).Clauses(clause.Locking{Strength: "UPDATE"}).Preload("TrainingBlocks").First(&training, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if err := checkOwner(user, training.OwnerID); err != nil {
+			return err
+		}
 
-	var blocks []models.Block
-	result = tuc.storage.DB.Preload("ExerciseBlocks").Preload("Exercises").Where("id IN ?", blockIds).Find(&blocks)
+		var err error
+		upgrades, err = blockUpgrades(tx, &training)
+		if err != nil || len(upgrades) == 0 {
+			return err
+		}
 
-	return &training, blocks, result.Error
+		draft, err := editableTraining(tx, &training)
+		if err != nil {
+			return err
+		}
+		training = draft
+
+		tbs := sortedTrainingBlocks(training.TrainingBlocks)
+		for _, u := range upgrades {
+			result = tx.Model(&models.TrainingBlock{}).Where("id = ?", tbs[u.Position].ID).Update("block_id", u.To.ID)
+			if result.Error != nil {
+				return result.Error
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, []models.Block{}, nil, err
+	}
+
+	upgraded, blocks, err := tuc.Find(user, int(training.ID))
+	return upgraded, blocks, upgrades, err
 }
 
 func (tuc *TrainingsUseCase) findNextOrder(tbs []models.TrainingBlock) uint {
@@ -148,20 +397,27 @@ func (tuc *TrainingsUseCase) updateTraining(tr models.Training, req requests.Tra
 	return &tr, nil
 }
 
-func (tuc *TrainingsUseCase) Create(req *requests.TrainingRequestBody) (*models.Training, error) {
-	var training models.Training
+// Create makes private training owned by user
+func (tuc *TrainingsUseCase) Create(user *models.User, req *requests.TrainingRequestBody) (*models.Training, error) {
+	training := models.Training{OwnerID: &user.ID, Visibility: VisibilityPrivate}
 	updatedTr, err := tuc.updateTraining(training, *req)
 	if err != nil {
 		return nil, err
 	}
 
 	result := tuc.storage.DB.Create(&updatedTr)
+	if result.Error != nil {
+		return updatedTr, result.Error
+	}
+
+	updatedTr.LineageID = updatedTr.ID
+	result = tuc.storage.DB.Model(updatedTr).Update("lineage_id", updatedTr.LineageID)
 	return updatedTr, result.Error
 }
 
-func (tuc *TrainingsUseCase) Find(id int) (*models.Training, []models.Block, error) {
+func (tuc *TrainingsUseCase) Find(user *models.User, id int) (*models.Training, []models.Block, error) {
 	var training models.Training
-	result := tuc.storage.DB.Preload("TrainingBlocks").First(&training, id)
+	result := tuc.storage.DB.Scopes(visibleTo(user, KindTraining)).Preload("TrainingBlocks").First(&training, id)
 	if result.Error != nil {
 		return nil, []models.Block{}, result.Error
 	}
@@ -172,45 +428,149 @@ func (tuc *TrainingsUseCase) Find(id int) (*models.Training, []models.Block, err
 	}
 
 	var blocks []models.Block
-	result = tuc.storage.DB.Preload("ExerciseBlocks").Preload("Exercises").Where("id IN ?", blockIds).Find(&blocks)
+	result = tuc.storage.DB.Preload("ExerciseBlocks").Preload("Exercises.Tags").Where("id IN ?", blockIds).Find(&blocks)
 
 	return &training, blocks, result.Error
 }
 
-func (tuc *TrainingsUseCase) Update(id int, req *requests.TrainingRequestBody) (*models.Training, []models.Block, error) {
+// Update changes draft training, published training is frozen so a new draft version is created and changed
+func (tuc *TrainingsUseCase) Update(user *models.User, id int, req *requests.TrainingRequestBody) (*models.Training, []models.Block, error) {
 	var training models.Training
-	result := tuc.storage.DB.Preload("TrainingBlocks").Preload("Blocks").First(&training, id)
-	if result.Error != nil {
-		return nil, []models.Block{}, result.Error
-	}
+	err := tuc.storage.DB.Transaction(func(tx *gorm.DB) error {
+		tbs, err := tuc.lockTrainingBlocks(tx, user, &training, uint(id))
+		if err != nil {
+			return err
+		}
+		if _, err = tuc.editableTrainingBlocks(tx, &training, tbs); err != nil {
+			return err
+		}
 
-	updatedTr, err := tuc.updateTraining(training, *req)
+		updatedTr, err := tuc.updateTraining(training, *req)
+		if err != nil {
+			return err
+		}
+
+		updatedTr.TrainingBlocks = nil
+		return tx.Omit(clause.Associations).Save(updatedTr).Error
+	})
 	if err != nil {
 		return nil, []models.Block{}, err
 	}
 
-	result = tuc.storage.DB.Save(&updatedTr)
-	if result.Error != nil {
-		return nil, []models.Block{}, result.Error
-	}
+	return tuc.Find(user, int(training.ID))
+}
 
-	blockIds := make([]uint, len(training.TrainingBlocks))
-	for i, t := range training.TrainingBlocks {
-		blockIds[i] = t.BlockID
+// Clone copies training visible to user into a new draft owned by user, shallow clone reuses the same blocks,
+// deep clone makes draft copy of every block too
+func (tuc *TrainingsUseCase) Clone(user *models.User, id int, req *requests.CloneTrainingRequestBody) (*models.Training, []models.Block, error) {
+	var clone models.Training
+	err := tuc.storage.DB.Transaction(func(tx *gorm.DB) error {
+		var training models.Training
+		result := tx.Scopes(visibleTo(user, KindTraining)).Preload("TrainingBlocks").First(&training, id)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		clone = models.Training{
+			TitleEn:    fmt.Sprintf("%s (copy)", training.TitleEn),
+			TitleRu:    fmt.Sprintf("%s (копия)", training.TitleRu),
+			Draft:      true,
+			Version:    1,
+			OwnerID:    &user.ID,
+			Visibility: VisibilityPrivate,
+		}
+		result = tx.Create(&clone)
+		if result.Error != nil {
+			return result.Error
+		}
+		result = tx.Model(&clone).Update("lineage_id", clone.ID)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		blockIDs := make(map[uint]uint)
+		for i, tb := range sortedTrainingBlocks(training.TrainingBlocks) {
+			blockID := tb.BlockID
+			if req.Deep {
+				if _, ok := blockIDs[tb.BlockID]; !ok {
+					var block models.Block
+					result = tx.Preload("ExerciseBlocks").First(&block, tb.BlockID)
+					if result.Error != nil {
+						return result.Error
+					}
+					clonedBlock, err := cloneBlock(tx, &block, user.ID)
+					if err != nil {
+						return err
+					}
+					blockIDs[tb.BlockID] = clonedBlock.ID
+				}
+				blockID = blockIDs[tb.BlockID]
+			}
+
+			result = tx.Create(&models.TrainingBlock{
+				TrainingID:     clone.ID,
+				BlockID:        blockID,
+				BlockOrder:     uint(i),
+				TransitionRest: tb.TransitionRest,
+				Repeats:        tb.Repeats,
+			})
+			if result.Error != nil {
+				return result.Error
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, []models.Block{}, err
 	}
 
-	var blocks []models.Block
-	result = tuc.storage.DB.Preload("ExerciseBlocks").Preload("Exercises").Where("id IN ?", blockIds).Find(&blocks)
+	return tuc.Find(user, int(clone.ID))
+}
 
-	return &training, blocks, result.Error
+func (tuc *TrainingsUseCase) Timeline(user *models.User, id int) (*models.Training, []Segment, error) {
+	training, blocks, err := tuc.Find(user, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	return training, TrainingTimeline(training, blocks), nil
 }
 
-func (tuc *TrainingsUseCase) ToggleDraft(id int) (*models.Training, []models.Block, error) {
+func (tuc *TrainingsUseCase) Validate(user *models.User, id int) ([]Issue, error) {
 	var training models.Training
-	result := tuc.storage.DB.Preload("TrainingBlocks").Preload("Blocks").First(&training, id)
+	result := tuc.storage.DB.Scopes(visibleTo(user, KindTraining)).Preload("TrainingBlocks").First(&training, id)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return tuc.validations.Training(&training)
+}
+
+func (tuc *TrainingsUseCase) ToggleDraft(user *models.User, id int) (*models.Training, []models.Block, error) {
+	var training models.Training
+	result := tuc.storage.DB.Scopes(visibleTo(user, KindTraining)).Preload("TrainingBlocks").Preload("Blocks").First(&training, id)
 	if result.Error != nil {
 		return nil, []models.Block{}, result.Error
 	}
+	if err := checkOwner(user, training.OwnerID); err != nil {
+		return nil, []models.Block{}, err
+	}
+
+	// publishing is refused while training has any error level issue
+	if training.Draft {
+		issues, err := tuc.validations.Training(&training)
+		if err != nil {
+			return nil, []models.Block{}, err
+		}
+		if HasErrors(issues) {
+			return nil, []models.Block{}, &NotReadyError{Err: ErrTrainingNotReady, Issues: issues}
+		}
+	}
+
+	// published training can be unpublished only while nothing relies on this version
+	if !training.Draft {
+		if err := checkTrainingNotPinned(tuc.storage.DB, training.ID); err != nil {
+			return nil, []models.Block{}, err
+		}
+	}
 
 	if training.Draft {
 		training.Draft = false
@@ -229,17 +589,23 @@ func (tuc *TrainingsUseCase) ToggleDraft(id int) (*models.Training, []models.Blo
 	}
 
 	var blocks []models.Block
-	result = tuc.storage.DB.Preload("ExerciseBlocks").Preload("Exercises").Where("id IN ?", blockIds).Find(&blocks)
+	result = tuc.storage.DB.Preload("ExerciseBlocks").Preload("Exercises.Tags").Where("id IN ?", blockIds).Find(&blocks)
 
 	return &training, blocks, result.Error
 }
 
-func (tuc *TrainingsUseCase) Delete(id int) error {
+func (tuc *TrainingsUseCase) Delete(user *models.User, id int) error {
 	var training *models.Training
-	result := tuc.storage.DB.First(&training, id)
+	result := tuc.storage.DB.Scopes(visibleTo(user, KindTraining)).First(&training, id)
 	if result.RowsAffected == 0 {
 		return gorm.ErrRecordNotFound
 	}
+	if err := checkOwner(user, training.OwnerID); err != nil {
+		return err
+	}
+	if err := checkTrainingNotScheduled(tuc.storage.DB, training.ID); err != nil {
+		return err
+	}
 
 	//delete all block training relations
 	var blockTrainingRelations []models.TrainingBlock
@@ -0,0 +1,160 @@
+package use_cases
+
+import (
+	"bf_me/internal/models"
+	"bf_me/internal/requests"
+	"bf_me/internal/services"
+	"bf_me/internal/storage"
+	"errors"
+	"fmt"
+	"net/mail"
+	"slices"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	RoleAdmin   = "admin"
+	RoleCoach   = "coach"
+	RoleAthlete = "athlete"
+)
+
+var Roles = []string{RoleAdmin, RoleCoach, RoleAthlete}
+
+var (
+	ErrInvalidRole      = errors.New("role should be one of admin, coach, athlete")
+	ErrInvalidUserLogin = errors.New("login and password should not be empty")
+	ErrLastAdmin        = errors.New("the last active admin can not be demoted or disabled")
+	ErrInvalidEmail     = errors.New("email is invalid")
+)
+
+type UsersUseCase struct {
+	storage *storage.Storage
+}
+
+func NewUsersUseCase(st *storage.Storage) *UsersUseCase {
+	return &UsersUseCase{storage: st}
+}
+
+func (uuc *UsersUseCase) List() ([]models.User, error) {
+	var users []models.User
+	result := uuc.storage.DB.Order("id").Find(&users)
+	return users, result.Error
+}
+
+func (uuc *UsersUseCase) Find(id int) (*models.User, error) {
+	var user models.User
+	result := uuc.storage.DB.First(&user, id)
+	return &user, result.Error
+}
+
+func (uuc *UsersUseCase) Create(req *requests.CreateUserRequestBody) (*models.User, error) {
+	role := req.Role
+	if role == "" {
+		role = RoleAthlete
+	}
+	return createUser(uuc.storage.DB, req.Login, req.Password, req.Email, role)
+}
+
+// Bootstrap creates admin with login and password when there is no admin yet, so the first admin
+// of a fresh deploy comes from configs. Empty login skips it
+func (uuc *UsersUseCase) Bootstrap(login, password string) error {
+	if login == "" {
+		return nil
+	}
+	return uuc.storage.DB.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		result := tx.Model(&models.User{}).Where("role = ?", RoleAdmin).Count(&count)
+		if result.Error != nil || count != 0 {
+			return result.Error
+		}
+		_, err := createUser(tx, login, password, "", RoleAdmin)
+		return err
+	})
+}
+
+func (uuc *UsersUseCase) SetRole(id int, role string) (*models.User, error) {
+	if !slices.Contains(Roles, role) {
+		return nil, ErrInvalidRole
+	}
+	return uuc.change(id, func(user *models.User) {
+		user.Role = role
+	})
+}
+
+// SetDisabled blocks or unblocks user, sessions of blocked user are ended
+func (uuc *UsersUseCase) SetDisabled(id int, disabled bool) (*models.User, error) {
+	return uuc.change(id, func(user *models.User) {
+		user.Disabled = disabled
+	})
+}
+
+// change applies update to locked user, there should be an active admin left after it
+func (uuc *UsersUseCase) change(id int, update func(user *models.User)) (*models.User, error) {
+	err := uuc.storage.DB.Transaction(func(tx *gorm.DB) error {
+		// admins are locked all together so that two of them can not demote each other at once
+		var admins []models.User
+		result := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("role = ? AND disabled = false", RoleAdmin).Find(&admins)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		var user models.User
+		result = tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		wasAdmin := user.Role == RoleAdmin && !user.Disabled
+		update(&user)
+		if wasAdmin && (user.Role != RoleAdmin || user.Disabled) && len(admins) <= 1 {
+			return ErrLastAdmin
+		}
+
+		result = tx.Model(&user).Updates(map[string]any{"role": user.Role, "disabled": user.Disabled})
+		if result.Error != nil {
+			return result.Error
+		}
+		if user.Disabled {
+			return tx.Where("user_id = ?", user.ID).Delete(&models.Session{}).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return uuc.Find(id)
+}
+
+func createUser(tx *gorm.DB, login, password, email, role string) (*models.User, error) {
+	if login == "" || password == "" {
+		return nil, ErrInvalidUserLogin
+	}
+	if !slices.Contains(Roles, role) {
+		return nil, ErrInvalidRole
+	}
+	if err := services.CheckPassword(password, login); err != nil {
+		return nil, err
+	}
+
+	u := models.User{Login: login, Role: role}
+	if email != "" {
+		address, err := mail.ParseAddress(email)
+		if err != nil {
+			return nil, ErrInvalidEmail
+		}
+		u.Email = &address.Address
+	}
+	var err error
+	u.PasswordHash, err = services.HashPassword(password)
+	if err != nil {
+		return nil, fmt.Errorf("password error: %s", err)
+	}
+
+	result := tx.Create(&u)
+	if result.Error != nil {
+		return nil, fmt.Errorf("create user error: %s", result.Error)
+	}
+	return &u, nil
+}
@@ -0,0 +1,399 @@
+package use_cases
+
+import (
+	"bf_me/internal/models"
+	"bf_me/internal/storage"
+	"fmt"
+	"slices"
+)
+
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+
+	EntityBlock    = "block"
+	EntityExercise = "exercise"
+	EntityTraining = "training"
+	EntityProgram  = "program"
+)
+
+// Issue is a single problem found while checking if entity can be published.
+// Issues with SeverityError block publishing, warnings are only informative.
+type Issue struct {
+	Code       string
+	Severity   string
+	EntityType string
+	EntityID   uint
+	Message    string
+}
+
+// NotReadyError is returned when publishing is refused, it keeps all found issues
+type NotReadyError struct {
+	Err    error
+	Issues []Issue
+}
+
+func (e *NotReadyError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *NotReadyError) Unwrap() error {
+	return e.Err
+}
+
+func HasErrors(issues []Issue) bool {
+	for _, i := range issues {
+		if i.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+type ValidationsUseCase struct {
+	storage *storage.Storage
+}
+
+func NewValidationsUseCase(st *storage.Storage) *ValidationsUseCase {
+	return &ValidationsUseCase{storage: st}
+}
+
+// Block expects block with preloaded ExerciseBlocks
+func (vuc *ValidationsUseCase) Block(block *models.Block) ([]Issue, error) {
+	issues := make([]Issue, 0)
+
+	if block.OnTime+block.RelaxTime == 0 {
+		issues = append(issues, Issue{
+			Code:       "block_timing_invalid",
+			Severity:   SeverityError,
+			EntityType: EntityBlock,
+			EntityID:   block.ID,
+			Message:    "block has no on time and relax time",
+		})
+		return issues, nil
+	}
+
+	period := int(block.OnTime) + int(block.RelaxTime)
+	if int(block.TotalDuration)*60%period != 0 {
+		issues = append(issues, Issue{
+			Code:       "block_timing_uneven",
+			Severity:   SeverityWarning,
+			EntityType: EntityBlock,
+			EntityID:   block.ID,
+			Message:    fmt.Sprintf("total duration %d min is not divisible by on time + relax time %d sec", block.TotalDuration, period),
+		})
+	}
+
+	capacity := blockCapacity(block)
+	count := len(block.ExerciseBlocks)
+	if count == 0 {
+		issues = append(issues, Issue{
+			Code:       "block_empty",
+			Severity:   SeverityError,
+			EntityType: EntityBlock,
+			EntityID:   block.ID,
+			Message:    "block has no exercises",
+		})
+	} else if count < capacity {
+		issues = append(issues, Issue{
+			Code:       "block_not_full",
+			Severity:   SeverityError,
+			EntityType: EntityBlock,
+			EntityID:   block.ID,
+			Message:    fmt.Sprintf("block has %d of %d exercises", count, capacity),
+		})
+	} else if count > capacity {
+		issues = append(issues, Issue{
+			Code:       "block_overfull",
+			Severity:   SeverityError,
+			EntityType: EntityBlock,
+			EntityID:   block.ID,
+			Message:    fmt.Sprintf("block has %d exercises but only %d fit", count, capacity),
+		})
+	}
+
+	exerciseIDs := make([]uint, 0, count)
+	for _, eb := range block.ExerciseBlocks {
+		exerciseIDs = append(exerciseIDs, eb.ExerciseID)
+	}
+	if len(exerciseIDs) == 0 {
+		return issues, nil
+	}
+
+	// unscoped to see exercises that were soft deleted after being added
+	var exercises []models.Exercise
+	result := vuc.storage.DB.Unscoped().Where("id IN ?", exerciseIDs).Find(&exercises)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	checked := make(map[uint]bool)
+	for _, id := range exerciseIDs {
+		if checked[id] {
+			continue
+		}
+		checked[id] = true
+
+		exercise, ok := vuc.findExercise(exercises, id)
+		if !ok || exercise.DeletedAt.Valid {
+			issues = append(issues, Issue{
+				Code:       "exercise_deleted",
+				Severity:   SeverityError,
+				EntityType: EntityExercise,
+				EntityID:   id,
+				Message:    fmt.Sprintf("exercise with id=%d was deleted", id),
+			})
+			continue
+		}
+
+		issues = append(issues, vuc.exerciseMedia(exercise)...)
+	}
+
+	issues = append(issues, vuc.sidesBalance(block, exercises)...)
+	return issues, nil
+}
+
+// sidesBalance flags exercises which are done more times for one side than for another
+func (vuc *ValidationsUseCase) sidesBalance(block *models.Block, exercises []models.Exercise) []Issue {
+	var issues []Issue
+	left := make(map[uint]int)
+	right := make(map[uint]int)
+	sideless := make(map[uint]int)
+	var order []uint
+	for _, eb := range block.ExerciseBlocks {
+		if left[eb.ExerciseID]+right[eb.ExerciseID]+sideless[eb.ExerciseID] == 0 {
+			order = append(order, eb.ExerciseID)
+		}
+		switch eb.Side {
+		case "left":
+			left[eb.ExerciseID]++
+		case "right":
+			right[eb.ExerciseID]++
+		default:
+			sideless[eb.ExerciseID]++
+		}
+	}
+
+	for _, id := range order {
+		exercise, _ := vuc.findExercise(exercises, id)
+		if left[id] != right[id] {
+			issues = append(issues, Issue{
+				Code:       "block_sides_unbalanced",
+				Severity:   SeverityWarning,
+				EntityType: EntityBlock,
+				EntityID:   block.ID,
+				Message:    fmt.Sprintf("exercise %q is done %d times for left side and %d times for right side", exercise.TitleEn, left[id], right[id]),
+			})
+		}
+		if exercise.Unilateral && sideless[id] != 0 {
+			issues = append(issues, Issue{
+				Code:       "exercise_side_missing",
+				Severity:   SeverityWarning,
+				EntityType: EntityBlock,
+				EntityID:   block.ID,
+				Message:    fmt.Sprintf("unilateral exercise %q has %d slots without side", exercise.TitleEn, sideless[id]),
+			})
+		}
+	}
+	return issues
+}
+
+func (vuc *ValidationsUseCase) exerciseMedia(exercise models.Exercise) []Issue {
+	if exercise.Filename == "" {
+		return []Issue{{
+			Code:       "exercise_media_missing",
+			Severity:   SeverityError,
+			EntityType: EntityExercise,
+			EntityID:   exercise.ID,
+			Message:    fmt.Sprintf("exercise %q has no media file", exercise.TitleEn),
+		}}
+	}
+	if vuc.storage.S3 == nil {
+		return nil
+	}
+
+	exists, err := vuc.storage.S3.Exists(exercise.Filename)
+	if err != nil {
+		return []Issue{{
+			Code:       "exercise_media_unverified",
+			Severity:   SeverityWarning,
+			EntityType: EntityExercise,
+			EntityID:   exercise.ID,
+			Message:    fmt.Sprintf("cannot check media file %s: %s", exercise.Filename, err),
+		}}
+	}
+	if !exists {
+		return []Issue{{
+			Code:       "exercise_media_missing",
+			Severity:   SeverityError,
+			EntityType: EntityExercise,
+			EntityID:   exercise.ID,
+			Message:    fmt.Sprintf("media file %s of exercise %q is gone", exercise.Filename, exercise.TitleEn),
+		}}
+	}
+	return nil
+}
+
+func (vuc *ValidationsUseCase) findExercise(exercises []models.Exercise, id uint) (models.Exercise, bool) {
+	for _, e := range exercises {
+		if e.ID == id {
+			return e, true
+		}
+	}
+	return models.Exercise{}, false
+}
+
+// Training expects training with preloaded TrainingBlocks
+func (vuc *ValidationsUseCase) Training(training *models.Training) ([]Issue, error) {
+	issues := make([]Issue, 0)
+
+	if len(training.TrainingBlocks) == 0 {
+		issues = append(issues, Issue{
+			Code:       "training_empty",
+			Severity:   SeverityError,
+			EntityType: EntityTraining,
+			EntityID:   training.ID,
+			Message:    "training has no blocks",
+		})
+		return issues, nil
+	}
+
+	blockIDs := make([]uint, len(training.TrainingBlocks))
+	for i, tb := range training.TrainingBlocks {
+		blockIDs[i] = tb.BlockID
+	}
+
+	var blocks []models.Block
+	result := vuc.storage.DB.Unscoped().Preload("ExerciseBlocks").Where("id IN ?", blockIDs).Find(&blocks)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	checked := make(map[uint]bool)
+	for _, id := range blockIDs {
+		if checked[id] {
+			continue
+		}
+		checked[id] = true
+
+		block, ok := vuc.findBlock(blocks, id)
+		if !ok || block.DeletedAt.Valid {
+			issues = append(issues, Issue{
+				Code:       "block_deleted",
+				Severity:   SeverityError,
+				EntityType: EntityBlock,
+				EntityID:   id,
+				Message:    fmt.Sprintf("block with id=%d was deleted", id),
+			})
+			continue
+		}
+
+		if block.Draft {
+			issues = append(issues, Issue{
+				Code:       "block_draft",
+				Severity:   SeverityError,
+				EntityType: EntityBlock,
+				EntityID:   block.ID,
+				Message:    fmt.Sprintf("block %q is draft, publish it first", block.TitleEn),
+			})
+		}
+
+		blockIssues, err := vuc.Block(&block)
+		if err != nil {
+			return nil, err
+		}
+		issues = append(issues, blockIssues...)
+	}
+
+	return issues, nil
+}
+
+func (vuc *ValidationsUseCase) findBlock(blocks []models.Block, id uint) (models.Block, bool) {
+	for _, b := range blocks {
+		if b.ID == id {
+			return b, true
+		}
+	}
+	return models.Block{}, false
+}
+
+// Program expects program with preloaded Days, trainings are published and checked on their own publishing
+func (vuc *ValidationsUseCase) Program(program *models.Program) ([]Issue, error) {
+	issues := make([]Issue, 0)
+
+	trainingIDs := make([]uint, 0, len(program.Days))
+	for _, d := range program.Days {
+		if d.TrainingID != nil {
+			trainingIDs = append(trainingIDs, *d.TrainingID)
+		}
+	}
+	if len(trainingIDs) == 0 {
+		issues = append(issues, Issue{
+			Code:       "program_empty",
+			Severity:   SeverityError,
+			EntityType: EntityProgram,
+			EntityID:   program.ID,
+			Message:    "program has no trainings",
+		})
+		return issues, nil
+	}
+
+	for week := uint8(0); week < program.Weeks; week++ {
+		trainings := 0
+		for _, d := range program.Days {
+			if d.Week == week && d.TrainingID != nil {
+				trainings++
+			}
+		}
+		if trainings == 0 {
+			issues = append(issues, Issue{
+				Code:       "program_week_empty",
+				Severity:   SeverityWarning,
+				EntityType: EntityProgram,
+				EntityID:   program.ID,
+				Message:    fmt.Sprintf("week %d has no trainings", week+1),
+			})
+		}
+	}
+
+	// unscoped to see trainings that were soft deleted after being scheduled
+	var trainings []models.Training
+	result := vuc.storage.DB.Unscoped().Where("id IN ?", trainingIDs).Find(&trainings)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	checked := make(map[uint]bool)
+	for _, id := range trainingIDs {
+		if checked[id] {
+			continue
+		}
+		checked[id] = true
+
+		index := slices.IndexFunc(trainings, func(t models.Training) bool {
+			return t.ID == id
+		})
+		if index == -1 || trainings[index].DeletedAt.Valid {
+			issues = append(issues, Issue{
+				Code:       "training_deleted",
+				Severity:   SeverityError,
+				EntityType: EntityTraining,
+				EntityID:   id,
+				Message:    fmt.Sprintf("training with id=%d was deleted", id),
+			})
+			continue
+		}
+
+		if trainings[index].Draft {
+			issues = append(issues, Issue{
+				Code:       "training_draft",
+				Severity:   SeverityError,
+				EntityType: EntityTraining,
+				EntityID:   id,
+				Message:    fmt.Sprintf("training %q is draft, publish it first", trainings[index].TitleEn),
+			})
+		}
+	}
+
+	return issues, nil
+}
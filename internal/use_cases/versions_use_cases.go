@@ -0,0 +1,294 @@
+package use_cases
+
+import (
+	"bf_me/internal/models"
+	"errors"
+	"fmt"
+	"slices"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrDraftVersionExists = errors.New("there is a newer draft version\nedit it instead")
+	ErrBlockVersionPinned = errors.New("published block version is used by trainings\nedit it to create a new draft version")
+	ErrTrainingPinned     = errors.New("published training version is used by programs, schedules, assignments or workouts\nedit it to create a new draft version")
+)
+
+// FieldChange is a difference of one field between two versions
+type FieldChange struct {
+	Field string
+	From  string
+	To    string
+}
+
+// BlockUpgrade describes replacing of pinned block version at Position of training with the latest published one
+type BlockUpgrade struct {
+	Position           uint
+	From               models.Block
+	To                 models.Block
+	Changes            []FieldChange
+	AddedExerciseIDs   []uint
+	RemovedExerciseIDs []uint
+}
+
+// editableBlock returns block itself if it is draft, otherwise creates a new draft version of it.
+// Block should have preloaded ExerciseBlocks, slots of new version keep the same order
+func editableBlock(tx *gorm.DB, block *models.Block) (models.Block, error) {
+	if block.Draft {
+		return *block, nil
+	}
+
+	version, err := nextBlockVersion(tx, block)
+	if err != nil {
+		return models.Block{}, err
+	}
+
+	return copyBlock(tx, block, models.Block{
+		TitleEn:       block.TitleEn,
+		TitleRu:       block.TitleRu,
+		TotalDuration: block.TotalDuration,
+		OnTime:        block.OnTime,
+		RelaxTime:     block.RelaxTime,
+		Draft:         true,
+		LineageID:     block.LineageID,
+		Version:       version,
+		OwnerID:       block.OwnerID,
+		Visibility:    block.Visibility,
+	})
+}
+
+func nextBlockVersion(tx *gorm.DB, block *models.Block) (uint, error) {
+	var drafts int64
+	result := tx.Model(&models.Block{}).Where("lineage_id = ? AND draft = ?", block.LineageID, true).Count(&drafts)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if drafts != 0 {
+		return 0, ErrDraftVersionExists
+	}
+
+	var version uint
+	result = tx.Unscoped().Model(&models.Block{}).Where("lineage_id = ?", block.LineageID).
+		Select("COALESCE(MAX(version), 0)").Scan(&version)
+	return version + 1, result.Error
+}
+
+// copyBlock creates header block and copies slots of block with preloaded ExerciseBlocks into it,
+// pairs of slots are kept
+func copyBlock(tx *gorm.DB, block *models.Block, header models.Block) (models.Block, error) {
+	result := tx.Create(&header)
+	if result.Error != nil {
+		return header, result.Error
+	}
+
+	ebs := sortedExerciseBlocks(block.ExerciseBlocks)
+	slotIDs := make(map[uint]uint, len(ebs))
+	copiedEbs := make([]models.ExerciseBlock, len(ebs))
+	for i, eb := range ebs {
+		copiedEbs[i] = models.ExerciseBlock{
+			ExerciseID:    eb.ExerciseID,
+			BlockID:       header.ID,
+			ExerciseOrder: uint(i),
+			Side:          eb.Side,
+		}
+		result = tx.Create(&copiedEbs[i])
+		if result.Error != nil {
+			return header, result.Error
+		}
+		slotIDs[eb.ID] = copiedEbs[i].ID
+	}
+
+	for i, eb := range ebs {
+		if eb.PairSlotID == nil {
+			continue
+		}
+		pairID, ok := slotIDs[*eb.PairSlotID]
+		if !ok {
+			continue
+		}
+		result = tx.Model(&copiedEbs[i]).Update("pair_slot_id", pairID)
+		if result.Error != nil {
+			return header, result.Error
+		}
+	}
+
+	header.ExerciseBlocks = copiedEbs
+	return header, nil
+}
+
+// checkBlockNotPinned is used before unpublishing, trainings rely on published versions being frozen
+func checkBlockNotPinned(tx *gorm.DB, blockID uint) error {
+	var count int64
+	result := tx.Model(&models.TrainingBlock{}).
+		Joins("INNER JOIN trainings ON trainings.id = training_blocks.training_id AND trainings.deleted_at IS NULL").
+		Where("training_blocks.block_id = ?", blockID).Count(&count)
+	if result.Error != nil {
+		return result.Error
+	}
+	if count != 0 {
+		return ErrBlockVersionPinned
+	}
+	return nil
+}
+
+// checkTrainingNotPinned is used before unpublishing, everything which refers to exact training version
+// relies on published versions being frozen
+func checkTrainingNotPinned(tx *gorm.DB, trainingID uint) error {
+	var pinned bool
+	result := tx.Raw(`SELECT EXISTS (SELECT 1 FROM program_days pd
+			JOIN programs p ON p.id = pd.program_id AND p.deleted_at IS NULL WHERE pd.training_id = @training)
+		OR EXISTS (SELECT 1 FROM workout_logs WHERE training_id = @training AND deleted_at IS NULL)
+		OR EXISTS (SELECT 1 FROM workout_runs WHERE training_id = @training AND deleted_at IS NULL)
+		OR EXISTS (SELECT 1 FROM assignments WHERE training_id = @training AND deleted_at IS NULL)
+		OR EXISTS (SELECT 1 FROM scheduled_sessions WHERE training_id = @training AND deleted_at IS NULL)`,
+		map[string]any{"training": trainingID}).Scan(&pinned)
+	if result.Error != nil {
+		return result.Error
+	}
+	if pinned {
+		return ErrTrainingPinned
+	}
+	return nil
+}
+
+// editableTraining returns training itself if it is draft, otherwise creates a new draft version of it.
+// Training should have preloaded TrainingBlocks
+func editableTraining(tx *gorm.DB, training *models.Training) (models.Training, error) {
+	if training.Draft {
+		return *training, nil
+	}
+
+	var drafts int64
+	result := tx.Model(&models.Training{}).Where("lineage_id = ? AND draft = ?", training.LineageID, true).Count(&drafts)
+	if result.Error != nil {
+		return models.Training{}, result.Error
+	}
+	if drafts != 0 {
+		return models.Training{}, ErrDraftVersionExists
+	}
+
+	var version uint
+	result = tx.Unscoped().Model(&models.Training{}).Where("lineage_id = ?", training.LineageID).
+		Select("COALESCE(MAX(version), 0)").Scan(&version)
+	if result.Error != nil {
+		return models.Training{}, result.Error
+	}
+
+	draft := models.Training{
+		TitleEn:    training.TitleEn,
+		TitleRu:    training.TitleRu,
+		Draft:      true,
+		LineageID:  training.LineageID,
+		Version:    version + 1,
+		OwnerID:    training.OwnerID,
+		Visibility: training.Visibility,
+	}
+	result = tx.Create(&draft)
+	if result.Error != nil {
+		return draft, result.Error
+	}
+
+	for i, tb := range sortedTrainingBlocks(training.TrainingBlocks) {
+		copied := models.TrainingBlock{
+			TrainingID:     draft.ID,
+			BlockID:        tb.BlockID,
+			BlockOrder:     uint(i),
+			TransitionRest: tb.TransitionRest,
+			Repeats:        tb.Repeats,
+		}
+		result = tx.Create(&copied)
+		if result.Error != nil {
+			return draft, result.Error
+		}
+		draft.TrainingBlocks = append(draft.TrainingBlocks, copied)
+	}
+	return draft, nil
+}
+
+// blockUpgrades compares every pinned block version of training with the latest published version of its lineage
+func blockUpgrades(tx *gorm.DB, training *models.Training) ([]BlockUpgrade, error) {
+	upgrades := make([]BlockUpgrade, 0)
+	for i, tb := range sortedTrainingBlocks(training.TrainingBlocks) {
+		var pinned models.Block
+		result := tx.Unscoped().Preload("ExerciseBlocks").First(&pinned, tb.BlockID)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+
+		var latest models.Block
+		result = tx.Preload("ExerciseBlocks").
+			Where("lineage_id = ? AND draft = ?", pinned.LineageID, false).
+			Order("version DESC").Limit(1).Find(&latest)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 0 || latest.Version <= pinned.Version {
+			continue
+		}
+
+		added, removed := exercisesDiff(pinned.ExerciseBlocks, latest.ExerciseBlocks)
+		upgrades = append(upgrades, BlockUpgrade{
+			Position:           uint(i),
+			From:               pinned,
+			To:                 latest,
+			Changes:            blockChanges(&pinned, &latest),
+			AddedExerciseIDs:   added,
+			RemovedExerciseIDs: removed,
+		})
+	}
+	return upgrades, nil
+}
+
+func blockChanges(from, to *models.Block) []FieldChange {
+	changes := make([]FieldChange, 0)
+	compare := func(field string, a, b any) {
+		if a != b {
+			changes = append(changes, FieldChange{Field: field, From: fmt.Sprint(a), To: fmt.Sprint(b)})
+		}
+	}
+	compare("titleEn", from.TitleEn, to.TitleEn)
+	compare("titleRu", from.TitleRu, to.TitleRu)
+	compare("totalDuration", from.TotalDuration, to.TotalDuration)
+	compare("onTime", from.OnTime, to.OnTime)
+	compare("relaxTime", from.RelaxTime, to.RelaxTime)
+
+	order := func(ebs []models.ExerciseBlock) string {
+		return fmt.Sprint(slotsExerciseIDs(sortedExerciseBlocks(ebs)))
+	}
+	compare("exercises", order(from.ExerciseBlocks), order(to.ExerciseBlocks))
+	return changes
+}
+
+// exercisesDiff counts every exercise, so adding the same exercise the second time is shown too
+func exercisesDiff(from, to []models.ExerciseBlock) ([]uint, []uint) {
+	counts := make(map[uint]int)
+	for _, eb := range from {
+		counts[eb.ExerciseID]--
+	}
+	for _, eb := range to {
+		counts[eb.ExerciseID]++
+	}
+
+	added := make([]uint, 0)
+	removed := make([]uint, 0)
+	for _, eb := range sortedExerciseBlocks(slices.Concat(from, to)) {
+		for counts[eb.ExerciseID] > 0 {
+			added = append(added, eb.ExerciseID)
+			counts[eb.ExerciseID]--
+		}
+		for counts[eb.ExerciseID] < 0 {
+			removed = append(removed, eb.ExerciseID)
+			counts[eb.ExerciseID]++
+		}
+	}
+	return added, removed
+}
+
+func slotsExerciseIDs(ebs []models.ExerciseBlock) []uint {
+	ids := make([]uint, len(ebs))
+	for i, eb := range ebs {
+		ids[i] = eb.ExerciseID
+	}
+	return ids
+}
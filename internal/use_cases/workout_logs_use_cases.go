@@ -0,0 +1,315 @@
+package use_cases
+
+import (
+	"bf_me/internal/models"
+	"bf_me/internal/requests"
+	"bf_me/internal/storage"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const MaxRPE = 10
+
+var (
+	ErrInvalidLogTimes   = errors.New("startedAt and finishedAt should be RFC 3339 times and finishedAt should not be before startedAt")
+	ErrSlotNotInTraining = errors.New("slot is not a part of training")
+	ErrInvalidRound      = errors.New("round is out of block repeats")
+	ErrInvalidRPE        = fmt.Errorf("perceived exertion should be from 1 to %d", MaxRPE)
+	ErrRunMismatch       = errors.New("run is of another training")
+	ErrRunLogged         = errors.New("run is already logged\nedit its log instead")
+)
+
+type WorkoutLogsUseCase struct {
+	storage *storage.Storage
+}
+
+func NewWorkoutLogsUseCase(st *storage.Storage) *WorkoutLogsUseCase {
+	return &WorkoutLogsUseCase{storage: st}
+}
+
+// trainingSlot is a slot of training at the moment of workout
+type trainingSlot struct {
+	blockID    uint
+	exerciseID uint
+	side       string
+	rounds     uint8
+}
+
+func (wuc *WorkoutLogsUseCase) List(userID uint, req *requests.FilterWorkoutLogsRequestBody) ([]models.WorkoutLog, error) {
+	query := wuc.storage.DB.Preload("Training", func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped()
+	}).Preload("Results").Where("user_id = ?", userID).Order("started_at DESC")
+
+	if req.From != "" {
+		from, err := time.Parse(time.RFC3339, req.From)
+		if err != nil {
+			return nil, ErrInvalidRange
+		}
+		query = query.Where("started_at >= ?", from)
+	}
+	if req.To != "" {
+		to, err := time.Parse(time.RFC3339, req.To)
+		if err != nil {
+			return nil, ErrInvalidRange
+		}
+		query = query.Where("started_at < ?", to)
+	}
+	if req.TrainingID != 0 {
+		query = query.Where("training_id = ?", req.TrainingID)
+	}
+
+	var logs []models.WorkoutLog
+	result := query.Find(&logs)
+	return logs, result.Error
+}
+
+// Find returns workout log only if it belongs to user
+func (wuc *WorkoutLogsUseCase) Find(userID uint, id int) (*models.WorkoutLog, error) {
+	var log models.WorkoutLog
+	result := wuc.storage.DB.Preload("Training", func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped()
+	}).Preload("Results", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id")
+	}).Where("user_id = ?", userID).First(&log, id)
+	return &log, result.Error
+}
+
+func (wuc *WorkoutLogsUseCase) Create(user *models.User, req *requests.WorkoutLogRequestBody) (*models.WorkoutLog, error) {
+	log := models.WorkoutLog{UserID: user.ID, TrainingID: req.TrainingID}
+	err := wuc.storage.DB.Transaction(func(tx *gorm.DB) error {
+		var run *models.WorkoutRun
+		if req.RunID != nil {
+			run = &models.WorkoutRun{}
+			// run is locked so concurrent requests do not log it twice
+			result := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("user_id = ?", user.ID).First(run, *req.RunID)
+			if result.Error != nil {
+				return result.Error
+			}
+			var logged int64
+			result = tx.Model(&models.WorkoutLog{}).Where("run_id = ?", run.ID).Count(&logged)
+			if result.Error != nil {
+				return result.Error
+			}
+			if logged != 0 {
+				return ErrRunLogged
+			}
+			if log.TrainingID == 0 {
+				log.TrainingID = run.TrainingID
+			}
+			if run.TrainingID != log.TrainingID {
+				return ErrRunMismatch
+			}
+			log.RunID = &run.ID
+			log.StartedAt = run.CreatedAt
+			log.FinishedAt = time.Now()
+			if run.FinishedAt != nil {
+				log.FinishedAt = *run.FinishedAt
+			}
+		}
+
+		var training models.Training
+		result := tx.Scopes(visibleTo(user, KindTraining)).First(&training, log.TrainingID)
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return ErrTrainingDeleted
+		}
+		if result.Error != nil {
+			return result.Error
+		}
+		if training.Draft {
+			return ErrTrainingNotPublished
+		}
+
+		if err := applyLogTimes(&log, req); err != nil {
+			return err
+		}
+		if req.Notes != nil {
+			log.Notes = *req.Notes
+		}
+		result = tx.Create(&log)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if run != nil && len(req.Results) == 0 {
+			return createRunResults(tx, &log, run)
+		}
+		return createLogResults(tx, &log, req.Results)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return wuc.Find(user.ID, int(log.ID))
+}
+
+// Update changes times and notes, not nil Results replace all results of log
+func (wuc *WorkoutLogsUseCase) Update(userID uint, id int, req *requests.WorkoutLogRequestBody) (*models.WorkoutLog, error) {
+	err := wuc.storage.DB.Transaction(func(tx *gorm.DB) error {
+		var log models.WorkoutLog
+		result := tx.Where("user_id = ?", userID).First(&log, id)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if err := applyLogTimes(&log, req); err != nil {
+			return err
+		}
+		if req.Notes != nil {
+			log.Notes = *req.Notes
+		}
+		result = tx.Omit("Training", "Results").Save(&log)
+		if result.Error != nil || req.Results == nil {
+			return result.Error
+		}
+
+		result = tx.Where("log_id = ?", log.ID).Delete(&models.WorkoutResult{})
+		if result.Error != nil {
+			return result.Error
+		}
+		return createLogResults(tx, &log, req.Results)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return wuc.Find(userID, id)
+}
+
+func (wuc *WorkoutLogsUseCase) Delete(userID uint, id int) error {
+	return wuc.storage.DB.Transaction(func(tx *gorm.DB) error {
+		var log models.WorkoutLog
+		result := tx.Where("user_id = ?", userID).First(&log, id)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		result = tx.Where("log_id = ?", log.ID).Delete(&models.WorkoutResult{})
+		if result.Error != nil {
+			return result.Error
+		}
+		return tx.Delete(&log).Error
+	})
+}
+
+// applyLogTimes sets given times, empty ones keep current values
+func applyLogTimes(log *models.WorkoutLog, req *requests.WorkoutLogRequestBody) error {
+	if req.StartedAt != "" {
+		startedAt, err := time.Parse(time.RFC3339, req.StartedAt)
+		if err != nil {
+			return ErrInvalidLogTimes
+		}
+		log.StartedAt = startedAt
+	}
+	if req.FinishedAt != "" {
+		finishedAt, err := time.Parse(time.RFC3339, req.FinishedAt)
+		if err != nil {
+			return ErrInvalidLogTimes
+		}
+		log.FinishedAt = finishedAt
+	}
+	if log.StartedAt.IsZero() || log.FinishedAt.Before(log.StartedAt) {
+		return ErrInvalidLogTimes
+	}
+	return nil
+}
+
+func createLogResults(tx *gorm.DB, log *models.WorkoutLog, reqs []requests.WorkoutResultRequest) error {
+	if len(reqs) == 0 {
+		return nil
+	}
+	slots, err := trainingSlots(tx, log.TrainingID)
+	if err != nil {
+		return err
+	}
+
+	results := make([]models.WorkoutResult, len(reqs))
+	for i, r := range reqs {
+		slot, ok := slots[r.SlotID]
+		if !ok {
+			return fmt.Errorf("%w: slot id=%d", ErrSlotNotInTraining, r.SlotID)
+		}
+		round := max(r.Round, 1)
+		if round > slot.rounds {
+			return fmt.Errorf("%w: slot id=%d round %d", ErrInvalidRound, r.SlotID, round)
+		}
+		if r.RPE != nil && (*r.RPE == 0 || *r.RPE > MaxRPE) {
+			return ErrInvalidRPE
+		}
+
+		results[i] = models.WorkoutResult{
+			LogID:      log.ID,
+			BlockID:    slot.blockID,
+			SlotID:     r.SlotID,
+			ExerciseID: slot.exerciseID,
+			Round:      round,
+			Side:       slot.side,
+			Reps:       r.Reps,
+			Load:       r.Load,
+			Skipped:    r.Skipped,
+			RPE:        r.RPE,
+		}
+	}
+	return tx.Create(&results).Error
+}
+
+// createRunResults records every work segment of run, the ones which were skipped or not reached are skipped
+func createRunResults(tx *gorm.DB, log *models.WorkoutLog, run *models.WorkoutRun) error {
+	segments, err := trainingSegments(tx, run.TrainingID)
+	if err != nil {
+		return err
+	}
+	state := RunStateAt(run, segments, log.FinishedAt)
+
+	results := make([]models.WorkoutResult, 0)
+	for i, s := range segments {
+		if s.Kind != SegmentWork || s.Exercise == nil {
+			continue
+		}
+		reached := int64(s.Start+s.Duration)*1000 <= state.Position
+		results = append(results, models.WorkoutResult{
+			LogID:      log.ID,
+			BlockID:    s.BlockID,
+			SlotID:     s.SlotID,
+			ExerciseID: s.Exercise.ID,
+			Round:      uint8(max(s.Round, 1)),
+			Side:       s.Side,
+			Skipped:    !reached || slices.Contains(run.SkippedSegments, int64(i)),
+		})
+	}
+	if len(results) == 0 {
+		return nil
+	}
+	return tx.Create(&results).Error
+}
+
+// trainingSlots maps slot id to its block version and rounds count of exact training version,
+// rounds of all occurrences of block are counted together the same way TrainingTimeline numbers them
+func trainingSlots(tx *gorm.DB, trainingID uint) (map[uint]trainingSlot, error) {
+	var training models.Training
+	result := tx.Unscoped().Preload("TrainingBlocks").First(&training, trainingID)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	rounds := make(map[uint]uint8)
+	blockIDs := make([]uint, 0, len(training.TrainingBlocks))
+	for _, tb := range training.TrainingBlocks {
+		rounds[tb.BlockID] += max(tb.Repeats, 1)
+		blockIDs = append(blockIDs, tb.BlockID)
+	}
+
+	var ebs []models.ExerciseBlock
+	result = tx.Where("block_id IN ?", blockIDs).Find(&ebs)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	slots := make(map[uint]trainingSlot, len(ebs))
+	for _, eb := range ebs {
+		slots[eb.ID] = trainingSlot{blockID: eb.BlockID, exerciseID: eb.ExerciseID, side: eb.Side, rounds: rounds[eb.BlockID]}
+	}
+	return slots, nil
+}
@@ -3,12 +3,15 @@ package main
 import (
 	"bf_me/internal/configs"
 	"bf_me/internal/routes"
+	"bf_me/internal/services"
 	"bf_me/internal/storage"
+	"bf_me/internal/use_cases"
 	"bf_me/pkg/database"
 	"bf_me/pkg/minio"
 	"github.com/rs/cors"
 	"log"
 	"net/http"
+	"time"
 )
 
 func main() {
@@ -30,19 +33,45 @@ func main() {
 	}
 
 	st := &storage.Storage{DB: db, S3: s3}
+
+	// ------- PASSWORDS AND MAIL -------
+	services.SetPasswordPolicy(services.PasswordPolicy(config.Password))
+	mailer := services.NewMailer(config.Mail.Addr, config.Mail.Username, config.Mail.Password, config.Mail.From)
+
+	// ------- FIRST ADMIN -------
+	err = use_cases.NewUsersUseCase(st).Bootstrap(config.Admin.Login, config.Admin.Password)
+	if err != nil {
+		log.Println(err)
+	}
 	mux := http.NewServeMux()
 
 	// ------- ROUTES -------
 	routes.RegisterSessionsRoutes(mux, st)
+	routes.RegisterPasswordsRoutes(mux, st, mailer, config.Mail.ResetURL)
+	routes.RegisterUsersRoutes(mux, st)
+	routes.RegisterAPIKeysRoutes(mux, st)
 	routes.RegisterExercisesRoutes(mux, st)
 	routes.RegisterBlocksRoutes(mux, st)
 	routes.RegisterTrainingsRoutes(mux, st)
+	routes.RegisterSharingRoutes(mux, st)
+	routes.RegisterProgramsRoutes(mux, st)
+	routes.RegisterSchedulesRoutes(mux, st)
+	routes.RegisterRunsRoutes(mux, st)
+	routes.RegisterWorkoutLogsRoutes(mux, st)
+	routes.RegisterAssignmentsRoutes(mux, st)
+	routes.RegisterAnalyticsRoutes(mux, st)
+
+	// ------- JOBS -------
+	use_cases.NewSessionsUseCase(st).StartSweeper(time.Hour)
 
 	// ------- SERVER -------
+	// any origin gets "*" which browsers refuse with credentials, so credentials need listed origins
 	c := cors.New(cors.Options{
+		AllowedOrigins:   config.AllowedOrigins,
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Content-Type", "Authorization", "Access-Control-Allow-Origin"},
-		AllowCredentials: true,
+		AllowedHeaders:   []string{"Content-Type", "Authorization", "X-CSRF-Token", "Access-Control-Allow-Origin"},
+		ExposedHeaders:   []string{"WWW-Authenticate"},
+		AllowCredentials: len(config.AllowedOrigins) != 0,
 	})
 	log.Fatal(http.ListenAndServe(config.Address, c.Handler(mux)))
 }
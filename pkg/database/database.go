@@ -26,16 +26,44 @@ func New(uri string) (*gorm.DB, error) {
 		return nil, fmt.Errorf("failed to enable extension for uuid: %s", err)
 	}
 
-	err = db.AutoMigrate(&models.User{}, &models.Session{})
+	hadRoles := db.Migrator().HasColumn(&models.User{}, "Role")
+	hadSessionExpiry := db.Migrator().HasColumn(&models.Session{}, "ExpiresAt")
+	hadSessionHashes := db.Migrator().HasColumn(&models.Session{}, "TokenHash")
+	err = db.AutoMigrate(&models.User{}, &models.Session{}, &models.APIKey{}, &models.PasswordReset{})
 	if err != nil {
 		return nil, fmt.Errorf("failed to migrate tables %s", err)
 	}
+	// sessions made before expiry had their ids as tokens and never ended, devices keep them
+	if !hadSessionExpiry {
+		err = migrateSessionIDTokens(db)
+		if err != nil {
+			return nil, fmt.Errorf("failed to migrate old sessions %s", err)
+		}
+	}
+	if !hadSessionHashes {
+		err = migrateSessionTokens(db)
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash session tokens %s", err)
+		}
+	}
+	// users registered before roles were the owners of the app
+	if !hadRoles {
+		result = db.Exec("UPDATE users SET role = 'admin'")
+		if result.Error != nil {
+			return nil, fmt.Errorf("failed to set roles of existing users %s", result.Error)
+		}
+	}
+
+	err = migrateExerciseBlocksPrimaryKey(db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to migrate exercise_blocks primary key %s", err)
+	}
 
 	err = db.SetupJoinTable(&models.Block{}, "Exercises", &models.ExerciseBlock{})
 	if err != nil {
 		return nil, fmt.Errorf("failed to set up join table between exercises and blocks tables %s", err)
 	}
-	err = db.AutoMigrate(&models.Exercise{}, &models.Block{}, &models.ExerciseBlock{})
+	err = db.AutoMigrate(&models.Exercise{}, &models.Tag{}, &models.Block{}, &models.ExerciseBlock{})
 	if err != nil {
 		return nil, fmt.Errorf("failed to migrate tables %s", err)
 	}
@@ -44,10 +72,99 @@ func New(uri string) (*gorm.DB, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to set up join table between exercises and blocks tables %s", err)
 	}
+	hadTransitionRest := db.Migrator().HasColumn(&models.TrainingBlock{}, "TransitionRest")
 	err = db.AutoMigrate(&models.Training{}, &models.TrainingBlock{})
 	if err != nil {
 		return nil, fmt.Errorf("failed to migrate tables %s", err)
 	}
+	// blocks added before transition rest was configurable had the default one of 60 seconds
+	if !hadTransitionRest {
+		result = db.Exec("UPDATE training_blocks SET transition_rest = 60")
+		if result.Error != nil {
+			return nil, fmt.Errorf("failed to set default transition rest %s", result.Error)
+		}
+	}
+
+	err = dedupeRunLogs(db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to remove repeated logs of runs %s", err)
+	}
+	err = db.AutoMigrate(&models.Program{}, &models.ProgramDay{}, &models.ScheduledSession{}, &models.WorkoutRun{}, &models.WorkoutLog{}, &models.WorkoutResult{}, &models.ContentShare{}, &models.Assignment{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to migrate tables %s", err)
+	}
+
+	// every block and training existed before versioning is the first version of its own lineage
+	result = db.Exec("UPDATE blocks SET lineage_id = id WHERE lineage_id = 0")
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to set blocks lineage %s", result.Error)
+	}
+	result = db.Exec("UPDATE trainings SET lineage_id = id WHERE lineage_id = 0")
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to set trainings lineage %s", result.Error)
+	}
 
 	return db, err
 }
+
+// migrateExerciseBlocksPrimaryKey replaces old composite primary key (id, exercise_id, block_id)
+// with id only, so the same exercise can be added to block several times
+func migrateExerciseBlocksPrimaryKey(db *gorm.DB) error {
+	if !db.Migrator().HasTable("exercise_blocks") {
+		return nil
+	}
+
+	var keyColumns int64
+	result := db.Raw(`SELECT count(*) FROM information_schema.key_column_usage k
+		JOIN information_schema.table_constraints c ON c.constraint_name = k.constraint_name AND c.table_name = k.table_name
+		WHERE c.table_name = 'exercise_blocks' AND c.constraint_type = 'PRIMARY KEY'`).Scan(&keyColumns)
+	if result.Error != nil {
+		return result.Error
+	}
+	if keyColumns <= 1 {
+		return nil
+	}
+
+	return db.Exec("ALTER TABLE exercise_blocks DROP CONSTRAINT exercise_blocks_pkey, ADD PRIMARY KEY (id)").Error
+}
+
+// dedupeRunLogs deletes all but the first log of every run, so a run can have only one log.
+// The index on run id which was not unique is replaced
+func dedupeRunLogs(db *gorm.DB) error {
+	if !db.Migrator().HasTable(&models.WorkoutLog{}) {
+		return nil
+	}
+
+	result := db.Exec(`UPDATE workout_logs SET deleted_at = now() WHERE deleted_at IS NULL AND run_id IS NOT NULL
+		AND id NOT IN (SELECT MIN(id) FROM workout_logs WHERE deleted_at IS NULL AND run_id IS NOT NULL GROUP BY run_id)`)
+	if result.Error != nil {
+		return result.Error
+	}
+	if db.Migrator().HasIndex(&models.WorkoutLog{}, "idx_workout_logs_run_id") {
+		return db.Migrator().DropIndex(&models.WorkoutLog{}, "idx_workout_logs_run_id")
+	}
+	return nil
+}
+
+// migrateSessionIDTokens hashes ids of sessions which were given to devices as tokens
+// and starts expiry of such sessions from now, like they were made on migration.
+// Hash is the same hex of SHA-256 which services.HashToken makes, lifetime is use_cases.SessionLifetime
+func migrateSessionIDTokens(db *gorm.DB) error {
+	return db.Exec(`UPDATE sessions SET token_hash = encode(sha256(convert_to(id::text, 'UTF8')), 'hex'),
+		created_at = now(), last_seen_at = now(), expires_at = now() + interval '90 days'
+		WHERE token_hash IS NULL OR token_hash = ''`).Error
+}
+
+// migrateSessionTokens replaces plain tokens of sessions with their hashes, so devices stay logged in.
+// Hash is the same hex of SHA-256 which services.HashToken makes
+func migrateSessionTokens(db *gorm.DB) error {
+	if !db.Migrator().HasColumn("sessions", "token") {
+		return nil
+	}
+
+	result := db.Exec("UPDATE sessions SET token_hash = encode(sha256(convert_to(token, 'UTF8')), 'hex') WHERE token IS NOT NULL")
+	if result.Error != nil {
+		return result.Error
+	}
+	return db.Migrator().DropColumn("sessions", "token")
+}
@@ -66,6 +66,24 @@ func (s *S3Storage) Delete(fname string) error {
 	return err
 }
 
+func (s *S3Storage) Exists(fname string) (bool, error) {
+	client, err := s.newConn()
+	if err != nil {
+		return false, err
+	}
+	_, err = client.StatObject(context.Background(),
+		s.config.Bucket,
+		strings.TrimPrefix(fname, s.config.Bucket+"/"), minio.StatObjectOptions{},
+	)
+	if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
 // Ping For debug purpose
 func (s *S3Storage) Ping() error {
 	client, err := s.newConn()